@@ -3,6 +3,7 @@ package azure
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -152,6 +153,9 @@ func (a *AzureAuth) TokenRefresh() {
 	}
 }
 
+// Wrapped by errors for things iServer doesn't have, as opposed to failing to ask it
+var ErrNotFound = errors.New("not found in iServer")
+
 func (a *AzureAuth) CallRestEndpoint(method string, path string, payload []byte, query string) (io.ReadCloser, error) {
 	for {
 		if len(a.AccessToken) > 0 {
@@ -186,7 +190,11 @@ func (a *AzureAuth) CallRestEndpoint(method string, path string, payload []byte,
 			}
 			resultMessage = string(bodyBytes)
 		}
-		return resp.Body, fmt.Errorf("iserver query failure, received %d\n%s\n%s", resp.StatusCode, newpath, resultMessage)
+		failure := fmt.Errorf("iserver query failure, received %d\n%s\n%s", resp.StatusCode, newpath, resultMessage)
+		if resp.StatusCode == http.StatusNotFound {
+			failure = fmt.Errorf("%w: %v", ErrNotFound, failure)
+		}
+		return resp.Body, failure
 	}
 	return nil, err
 }
@@ -6,6 +6,7 @@ import (
 	"io"
 	"log"
 	"net/url"
+	"sort"
 	"strings"
 	"time"
 )
@@ -153,13 +154,24 @@ type RelationshipStruct struct {
 		Name                  string `json:"Name"`
 		Description           string `json:"Description"`
 	} `json:"RelationshipType"`
-	LeadObject   ObjectTypeStruct `json:"LeadObject"`
-	MemberObject ObjectTypeStruct `json:"MemberObject"`
+	LeadObject      RelatedObjectStruct `json:"LeadObject"`
+	MemberObject    RelatedObjectStruct `json:"MemberObject"`
+	AttributeValues []AttributeValue    `json:"AttributeValues"`
+	CreatedBy       UserStruct          `json:"CreatedBy"`
+	LastModifiedBy  UserStruct          `json:"LastModifiedBy"`
+}
+
+// The end of a relationship, with whichever attributes were expanded
+type RelatedObjectStruct struct {
+	Name            string                `json:"Name"`
+	ObjectTypeId    string                `json:"ObjectTypeId"`
+	AttributeValues []AttributeTypeStruct `json:"AttributeValues"`
 }
 
 type MinRelationship struct {
 	RelationshipID        string
 	RelationshipType      string
+	RelationshipTypeName  string
 	LeadObjectID          string
 	LeadObjectName        string
 	MemberObjectID        string
@@ -168,14 +180,18 @@ type MinRelationship struct {
 }
 
 type RelationshipTypeStruct struct {
-	RelationshipTypeId    string `json:"RelationshipTypeId"`
-	ActiveState           bool   `json:"ActiveState"`
-	Direction             string `json:"Direction"`
-	Name                  string `json:"Name"`
-	RelationshipTypePairs []struct {
-		RelationshipTypePairId string `json:"RelationshipTypePairId"`
-		LeadObjectTypeId       string `json:"LeadObjectTypeId"`
-	} `json:"RelationshipTypePairs,omitempty"`
+	RelationshipTypeId    string                 `json:"RelationshipTypeId"`
+	ActiveState           bool                   `json:"ActiveState"`
+	Direction             string                 `json:"Direction"`
+	Name                  string                 `json:"Name"`
+	RelationshipTypePairs []RelationshipTypePair `json:"RelationshipTypePairs,omitempty"`
+}
+
+// The object types a relationship type can join, one leading and one the member
+type RelationshipTypePair struct {
+	RelationshipTypePairId string `json:"RelationshipTypePairId"`
+	LeadObjectTypeId       string `json:"LeadObjectTypeId"`
+	MemberObjectTypeId     string `json:"MemberObjectTypeId"`
 }
 
 func (a *AzureAuth) GetObjectsByCategory(category string, attributes []string) map[string]ObjectStruct {
@@ -433,83 +449,6 @@ func (a *AzureAuth) GetObjectsForTypeAndArea(objectType string, owners []string)
 	return toReturn
 }
 
-func (a *AzureAuth) GetObjectsForTypeAndDepartmentWithoutOwners(objectType string, department string, owners []string) []ObjectStruct {
-	type objects struct {
-		Value    []ObjectStruct `json:"value"`
-		NextLink string         `json:"@odata.nextLink"`
-	}
-	toReturn := []ObjectStruct{}
-	filterQuery := ""
-	bob := owners
-	for _, owner := range bob {
-		if strings.Contains(owner, "&") {
-			owners = append(owners, strings.Replace(owner, "&", "and", -1))
-		} else if strings.Contains(owner, "and") {
-			owners = append(owners, strings.Replace(owner, "and", "&", -1))
-		}
-	}
-	filterQuery = fmt.Sprintf(
-		` and AttributeValues/OfficeArchitect.Contracts.OData.Model.AttributeValue.AttributeValueChoice/any(a:a/AttributeName eq '%s' and a/Values/any(b:b/Value eq '%s'))`+
-			` and AttributeValues/OfficeArchitect.Contracts.OData.Model.Attributevalue.AttributeValueChoice/any(a:a/AttributeName eq 'Lifecycle Status' and a/Values/any(b:b/value in ('Proposed','In Development','Live','Phasing Out')))`,
-		"GU::Domain",
-		strings.Replace(department, "&", "%26", -1),
-	)
-	path := "/odata/Objects"
-	query := fmt.Sprintf(`$expand=ObjectType($select=Name),AttributeValues($select=StringValue,AttributeName;$filter=AttributeName in ('Lifecycle Status','IServerID','Description','Owner','GU::Review Bodies','Owner (Legacy)','Internal Recommendation','Operational Importance','Department'))&`+
-		`$filter=Model/Name eq '%s'`+
-		` and ObjectType/Name in ('Physical Application Component','Physical Technology Component','Logical Application Component')`+
-		`%s`,
-		defaultModel,
-		filterQuery)
-	query = strings.Replace(query, " ", "%20", -1)
-	for {
-		var oneCall objects
-		mep, err := a.CallRestEndpoint("GET", path, []byte{}, query)
-		if err != nil {
-			log.Fatalf("failed to call endpoint %v\n", err)
-		}
-		defer mep.Close()
-		bytemep, err := io.ReadAll(mep)
-		json.Unmarshal(bytemep, &oneCall)
-
-		if err != nil {
-			log.Fatalf("failed to read io.Reader %v\n", err)
-		}
-		for _, anObject := range oneCall.Value {
-			if unknownProductManager(anObject, owners) {
-				toReturn = append(toReturn, anObject)
-			}
-		}
-		if len(oneCall.NextLink) == 0 {
-			break
-		}
-		bits, err := url.Parse(oneCall.NextLink)
-		if err != nil {
-			log.Printf("Failed to parse next")
-			break
-		}
-		path = bits.Path
-		query = bits.RawQuery
-		time.Sleep(100 * time.Millisecond)
-	}
-	return toReturn
-}
-
-func unknownProductManager(needle ObjectStruct, haystack []string) bool {
-	for _, a := range needle.Attributevalues {
-		if a.AttributeName == "Owner" {
-			for _, v := range haystack {
-				if a.StringValue == v {
-					//fmt.Printf("Do not add [%s] vs [%s]\n", a.StringValue, v)
-					return false
-				}
-			}
-			fmt.Printf("Add [%s]\n", a.StringValue)
-		}
-	}
-	return true
-}
-
 func (a *AzureAuth) GetRelationTypesForObjectType(objectTypeId1, objectTypeId2 string) map[string]RelationshipTypeStruct {
 	type objects struct {
 		Value    []RelationshipTypeStruct `json:"value"`
@@ -570,6 +509,44 @@ func (a *AzureAuth) DeleteARelationship(id string) error {
 	return err
 }
 
+// Create a relationship in the baseline model between two existing objects
+func (a *AzureAuth) CreateRelationship(relationshipTypeId, relationshipTypePairId, leadObjectId, memberObjectId string) error {
+	body := fmt.Sprintf(
+		`{"RelationshipTypeId":"%s",
+		"ModelId":"%s",
+		"RelationshipTypePairId":"%s",
+		"LeadModelItemId":"%s",
+		"MemberModelItemId":"%s"}`,
+		relationshipTypeId,
+		BaselineArchitectureModel,
+		relationshipTypePairId,
+		leadObjectId,
+		memberObjectId,
+	)
+	mep, err := a.CallRestEndpoint("POST", "/odata/Relationships", []byte(body), "")
+	if err != nil {
+		return err
+	}
+	defer mep.Close()
+	_, err = io.ReadAll(mep)
+	return err
+}
+
+// The iServer id for a named object type, blank if unknown
+func ObjectTypeIdFor(name string) string {
+	return objectTypesList[name]
+}
+
+// Every object type relationships can be made between, by name
+func ObjectTypeNames() []string {
+	toReturn := []string{}
+	for i := range objectTypesList {
+		toReturn = append(toReturn, i)
+	}
+	sort.Strings(toReturn)
+	return toReturn
+}
+
 type ODataMessage struct {
 	MessageCategory   string `json:"messageCategory"`
 	MessageCode       string `json:"messageCode"`
@@ -587,7 +564,12 @@ type ODataResponse struct {
 
 // 2025
 
-func (a *AzureAuth) GetPACForRSDFDomain() []ObjectStruct {
+// Quote a GU::Domain choice for use inside an OData filter that has its spaces replaced later
+func domainFilterValue(domain string) string {
+	return strings.ReplaceAll(url.QueryEscape(strings.ReplaceAll(domain, "'", "''")), "+", " ")
+}
+
+func (a *AzureAuth) GetPACForDomain(domain string) []ObjectStruct {
 	type objects struct {
 		Value    []ObjectStruct `json:"value"`
 		NextLink string         `json:"@odata.nextLink"`
@@ -597,9 +579,10 @@ func (a *AzureAuth) GetPACForRSDFDomain() []ObjectStruct {
 	query := fmt.Sprintf(
 		`$filter=Model/Name eq '%s'`+
 			` and ObjectType/Name eq 'Physical Application Component'`+
-			` and AttributeValues/OfficeArchitect.Contracts.OData.Model.AttributeValue.AttributeValueChoice/any(a:a/AttributeName eq 'GU::Domain' and a/Values/any(b:b/Value eq 'Research, Specialised & Data Foundations'))`+
+			` and AttributeValues/OfficeArchitect.Contracts.OData.Model.AttributeValue.AttributeValueChoice/any(a:a/AttributeName eq 'GU::Domain' and a/Values/any(b:b/Value eq '%s'))`+
 			` and AttributeValues/OfficeArchitect.Contracts.OData.Model.AttributeValue.AttributeValueChoice/any(a:a/AttributeName eq 'Lifecycle Status' and a/Values/any(b:b/Value in ('In Development','Live')))`,
-		defaultModel)
+		defaultModel,
+		domainFilterValue(domain))
 	query = strings.Replace(query, " ", "%20", -1)
 	for {
 		var oneCall objects
@@ -630,7 +613,7 @@ func (a *AzureAuth) GetPACForRSDFDomain() []ObjectStruct {
 	return toReturn
 }
 
-func (a *AzureAuth) GetDomainObjectsForHERM() []ObjectStruct {
+func (a *AzureAuth) GetDomainObjectsForHERM(domain string) []ObjectStruct {
 	// * PAC - Our specific applications
 	type objects struct {
 		Value    []ObjectStruct `json:"value"`
@@ -641,9 +624,10 @@ func (a *AzureAuth) GetDomainObjectsForHERM() []ObjectStruct {
 	query := fmt.Sprintf(
 		`$filter=Model/Name eq '%s'`+
 			` and ObjectType/Name eq 'Physical Application Component'`+
-			` and AttributeValues/OfficeArchitect.Contracts.OData.Model.AttributeValue.AttributeValueChoice/any(a:a/AttributeName eq 'GU::Domain' and a/Values/any(b:b/Value eq 'Research, Specialised %%26 Data Foundations'))`+
+			` and AttributeValues/OfficeArchitect.Contracts.OData.Model.AttributeValue.AttributeValueChoice/any(a:a/AttributeName eq 'GU::Domain' and a/Values/any(b:b/Value eq '%s'))`+
 			` and AttributeValues/OfficeArchitect.Contracts.OData.Model.AttributeValue.AttributeValueChoice/any(a:a/AttributeName eq 'Lifecycle Status' and a/Values/any(b:b/Value in ('In Development','Live')))`,
-		defaultModel)
+		defaultModel,
+		domainFilterValue(domain))
 	query = strings.Replace(query, " ", "%20", -1)
 	for {
 		var oneCall objects
@@ -674,13 +658,13 @@ func (a *AzureAuth) GetDomainObjectsForHERM() []ObjectStruct {
 	return toReturn
 }
 
-func (a *AzureAuth) GetRelatedHERMObjects(objectsin []ObjectStruct) ([]ObjectStruct, []MinRelationship) {
+func (a *AzureAuth) GetRelatedHERMObjects(objectsin []ObjectStruct, relatedTypes []string) ([]ObjectStruct, []MinRelationship) {
 	// PAC links to PAC, LAC, PDC, PTC, CAP
 	// * CAP - BCM
 	// * LAC - ARM
-	// LAC links to LTC @todo
+	// LAC links to LTC
 	// * LTC - TRM
-	// PDC links to LDC @todo
+	// PDC links to LDC
 	// * LDC - DRM
 	type objects struct {
 		Value    []RelationshipStruct `json:"value"`
@@ -690,86 +674,130 @@ func (a *AzureAuth) GetRelatedHERMObjects(objectsin []ObjectStruct) ([]ObjectStr
 	uniqueObjects := map[string]ObjectStruct{}
 	toReturnRelations := []MinRelationship{}
 	uniqueRelations := map[string]RelationshipStruct{}
+	wanted := map[string]bool{}
+	for _, x := range relatedTypes {
+		wanted[x] = true
+	}
+	relatedSelect := `$select=Name,ObjectTypeId;$expand=AttributeValues($select=StringValue,AttributeName;$filter=AttributeName eq 'Lifecycle Status')`
+	// Relationships in either direction between the given objects and any of the given types
+	queriesFor := func(objectIds []string, typeIds []string) []string {
+		return []string{
+			fmt.Sprintf(
+				`$expand=RelationshipType($select=Name,LeadToMemberDirection),LeadObject(%[4]s),MemberObject(%[4]s)&`+
+					`$filter=Model/Name eq '%[1]s'`+
+					` and LeadObjectId in (%[2]s) and MemberObject/ObjectTypeId in (%[3]s)`,
+				defaultModel,
+				strings.Join(objectIds, ","),
+				strings.Join(typeIds, ","),
+				relatedSelect,
+			),
+			fmt.Sprintf(
+				`$expand=RelationshipType($select=Name,LeadToMemberDirection),LeadObject(%[4]s),MemberObject(%[4]s)&`+
+					`$filter=Model/Name eq '%[1]s'`+
+					` and MemberObjectId in (%[2]s) and LeadObject/ObjectTypeId in (%[3]s)`,
+				defaultModel,
+				strings.Join(objectIds, ","),
+				strings.Join(typeIds, ","),
+				relatedSelect,
+			),
+		}
+	}
+	collect := func(queries []string) {
+		for _, query := range queries {
+			path := "/odata/Relationships"
+			query = strings.Replace(query, " ", "%20", -1)
+			for {
+				var oneCall objects
+				mep, err := a.CallRestEndpoint("GET", path, []byte{}, query)
+				if err != nil {
+					log.Fatalf("failed to call endpoint %v\n", err)
+				}
+				defer mep.Close()
+				bytemep, err := io.ReadAll(mep)
+				json.Unmarshal(bytemep, &oneCall)
+
+				if err != nil {
+					log.Fatalf("failed to read io.Reader %v\n", err)
+				}
+				for _, x := range oneCall.Value {
+					uniqueRelations[x.RelationshipId] = x
+					uniqueObjects[x.LeadObjectId] = ObjectStruct{ObjectID: x.LeadObjectId, Name: x.LeadObject.Name, ObjectTypeId: x.LeadObject.ObjectTypeId, ObjectType: ObjectTypeStruct{Name: ObjectTypesListLookup[x.LeadObject.ObjectTypeId]}, Attributevalues: x.LeadObject.AttributeValues}
+					uniqueObjects[x.MemberObjectId] = ObjectStruct{ObjectID: x.MemberObjectId, Name: x.MemberObject.Name, ObjectTypeId: x.MemberObject.ObjectTypeId, ObjectType: ObjectTypeStruct{Name: ObjectTypesListLookup[x.MemberObject.ObjectTypeId]}, Attributevalues: x.MemberObject.AttributeValues}
+				}
+				if len(oneCall.NextLink) == 0 {
+					break
+				}
+				bits, err := url.Parse(oneCall.NextLink)
+				if err != nil {
+					log.Printf("Failed to parse next")
+					break
+				}
+				path = bits.Path
+				query = bits.RawQuery
+				time.Sleep(200 * time.Millisecond)
+			}
+		}
+	}
+	idsOfType := func(typeName string) []string {
+		toReturn := []string{}
+		for _, x := range uniqueObjects {
+			if x.ObjectType.Name == typeName {
+				toReturn = append(toReturn, x.ObjectID)
+			}
+		}
+		sort.Strings(toReturn)
+		return toReturn
+	}
+
 	// Convert objectsin to just ids
 	objectIds := []string{}
 	for _, x := range objectsin {
 		objectIds = append(objectIds, x.ObjectID)
 	}
-	relatedObjects := []string{
-		objectTypesList["Logical Application Component"],
-		objectTypesList["Physical Data Component"],
-		objectTypesList["Physical Technology Component"],
+	secondHops := []struct{ from, to string }{
+		{"Logical Application Component", "Logical Technology Component"},
+		{"Physical Data Component", "Logical Data Component"},
 	}
-
-	path := "/odata/Relationships"
-	for _, query := range []string{
-		fmt.Sprintf(
-			`$expand=LeadObject($select=Name,ObjectTypeId),MemberObject($select=Name,ObjectTypeId)&`+
-				`$filter=Model/Name eq '%s'`+
-				` and LeadObjectId in (%s) and MemberObject/ObjectTypeId in (%s)`,
-			defaultModel,
-			strings.Join(objectIds, ","),
-			strings.Join(relatedObjects, ","),
-		),
-		fmt.Sprintf(
-			`$expand=LeadObject($select=Name,ObjectTypeId),MemberObject($select=Name,ObjectTypeId)&`+
-				`$filter=Model/Name eq '%s'`+
-				` and MemberObjectId in (%s) and LeadObject/ObjectTypeId in (%s)`,
-			defaultModel,
-			strings.Join(objectIds, ","),
-			strings.Join(relatedObjects, ","),
-		),
-		fmt.Sprintf(
-			`$expand=LeadObject($select=Name,ObjectTypeId),MemberObject($select=Name,ObjectTypeId)&`+
-				`$filter=Model/Name eq '%s'`+
-				` and LeadObjectId in (%s) and MemberObject/ObjectTypeId in (%s)`,
-			defaultModel,
-			strings.Join(objectIds, ","),
-			objectTypesList["Capability"],
-		),
-		fmt.Sprintf(
-			`$expand=LeadObject($select=Name,ObjectTypeId),MemberObject($select=Name,ObjectTypeId)&`+
-				`$filter=Model/Name eq '%s'`+
-				` and MemberObjectId in (%s) and LeadObject/ObjectTypeId in (%s)`,
-			defaultModel,
-			strings.Join(objectIds, ","),
-			objectTypesList["Capability"],
-		),
-	} {
-		query = strings.Replace(query, " ", "%20", -1)
-		for {
-			var oneCall objects
-			mep, err := a.CallRestEndpoint("GET", path, []byte{}, query)
-			if err != nil {
-				log.Fatalf("failed to call endpoint %v\n", err)
-			}
-			defer mep.Close()
-			bytemep, err := io.ReadAll(mep)
-			json.Unmarshal(bytemep, &oneCall)
-
-			if err != nil {
-				log.Fatalf("failed to read io.Reader %v\n", err)
-			}
-			for _, x := range oneCall.Value {
-				uniqueRelations[x.RelationshipId] = x
-				uniqueObjects[x.LeadObjectId] = ObjectStruct{ObjectID: x.LeadObjectId, Name: x.LeadObject.Name, ObjectType: ObjectTypeStruct{Name: ObjectTypesListLookup[x.LeadObject.ObjectTypeId]}}
-				uniqueObjects[x.MemberObjectId] = ObjectStruct{ObjectID: x.MemberObjectId, Name: x.MemberObject.Name, ObjectType: ObjectTypeStruct{Name: ObjectTypesListLookup[x.MemberObject.ObjectTypeId]}}
-			}
-			if len(oneCall.NextLink) == 0 {
-				break
-			}
-			bits, err := url.Parse(oneCall.NextLink)
-			if err != nil {
-				log.Printf("Failed to parse next")
-				break
-			}
-			path = bits.Path
-			query = bits.RawQuery
-			time.Sleep(200 * time.Millisecond)
+	// The LACs and PDCs are needed to reach the LTCs and LDCs, even when they aren't wanted themselves
+	for _, hop := range secondHops {
+		if wanted[hop.to] {
+			wanted[hop.from] = true
 		}
 	}
+	// Capabilities are queried on their own, the rest together
+	relatedObjects := []string{}
+	for _, x := range []string{"Logical Application Component", "Physical Data Component", "Physical Technology Component"} {
+		if wanted[x] {
+			relatedObjects = append(relatedObjects, objectTypesList[x])
+		}
+	}
+	if len(relatedObjects) > 0 {
+		collect(queriesFor(objectIds, relatedObjects))
+	}
+	if wanted["Capability"] {
+		collect(queriesFor(objectIds, []string{objectTypesList["Capability"]}))
+	}
+	// Second hop into the technology and data reference models
+	for _, hop := range secondHops {
+		if !wanted[hop.to] {
+			continue
+		}
+		if fromIds := idsOfType(hop.from); len(fromIds) > 0 {
+			collect(queriesFor(fromIds, []string{objectTypesList[hop.to]}))
+		}
+	}
+
 	for _, x := range uniqueRelations {
-		toReturnRelations = append(toReturnRelations, MinRelationship{LeadObjectID: x.LeadObjectId, MemberObjectID: x.MemberObjectId, RelationshipType: ObjectTypesListLookup[x.RelationshipTypeId]})
+		toReturnRelations = append(toReturnRelations, MinRelationship{
+			RelationshipID:        x.RelationshipId,
+			RelationshipType:      x.RelationshipTypeId,
+			RelationshipTypeName:  x.RelationshipType.Name,
+			LeadObjectID:          x.LeadObjectId,
+			LeadObjectName:        x.LeadObject.Name,
+			MemberObjectID:        x.MemberObjectId,
+			MemberObjectName:      x.MemberObject.Name,
+			LeadToMemberDirection: x.RelationshipType.LeadToMemberDirection,
+		})
 	}
 	for _, x := range uniqueObjects {
 		toReturnObjects = append(toReturnObjects, x)
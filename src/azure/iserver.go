@@ -6,14 +6,12 @@ import (
 	"io"
 	"log"
 	"net/url"
-	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
 	"time"
 
 	fyne "fyne.io/fyne/v2"
-	"github.com/xuri/excelize/v2"
 )
 
 type FindStruct struct {
@@ -27,6 +25,13 @@ type FindStruct struct {
 
 var ValidChoices = map[string]map[string]string{}
 
+// Links are written as Name (url), one per line or comma separated
+var LinkPattern = regexp.MustCompile(`^\s*(.*?)\s*\((.*)\)$\s*`)
+
+func SplitLinks(links string) []string {
+	return strings.Split(strings.ReplaceAll(links, "\n", ","), ",")
+}
+
 var BaselineArchitectureModel = "0bb71446-f140-ea11-a601-28187852aafd"
 
 type ValuesValue struct {
@@ -67,19 +72,39 @@ type IServerObjectStruct struct {
 		Name string `json:"Name"`
 		Id   string `json:"ObjectTypeId"`
 	} `json:"ObjectType"`
+	LastModifiedDate string     `json:"LastModifiedDate"`
+	LastModifiedById string     `json:"LastModifiedById"`
+	LastModifiedBy   UserStruct `json:"LastModifiedBy"`
+}
+
+// An iServer user, when CreatedBy or LastModifiedBy is expanded
+type UserStruct struct {
+	FirstName string `json:"FirstName"`
+	LastName  string `json:"LastName"`
+	Email     string `json:"Email"`
+}
+
+// The user's name, or their email if iServer has no name for them
+func (x UserStruct) String() string {
+	if name := strings.TrimSpace(x.FirstName + " " + x.LastName); name != "" {
+		return name
+	}
+	return x.Email
 }
 
 type RelationStruct struct {
-	RelationshipId   string `json:"RelationshipId"`
-	LeadObjectId     string `json:"LeadObjectId"`
-	MemberObjectId   string `json:"MemberObjectId"`
-	RelationshipType struct {
+	RelationshipId         string `json:"RelationshipId"`
+	RelationshipTypePairId string `json:"RelationshipTypePairId"`
+	LeadObjectId           string `json:"LeadObjectId"`
+	MemberObjectId         string `json:"MemberObjectId"`
+	RelationshipType       struct {
 		Name                  string `json:"Name"`
 		RelationshipTypeId    string `json:"RelationshipTypeId"`
 		LeadToMemberDirection string `json:"LeadToMemberDirection"`
 	} `json:"RelationshipType"`
-	LeadObject   FindStruct `json:"LeadObject"`
-	MemberObject FindStruct `json:"MemberObject"`
+	LeadObject      FindStruct       `json:"LeadObject"`
+	MemberObject    FindStruct       `json:"MemberObject"`
+	AttributeValues []AttributeValue `json:"AttributeValues"`
 }
 
 type laterLongUpdate func([]FindStruct, *fyne.Window)
@@ -87,73 +112,11 @@ type laterRelationUpdate func(IServerObjectStruct, []RelationStruct, *fyne.Windo
 type laterStringList func(map[string][]string, *fyne.Window)
 type laterDomainOwned func(map[string][]IServerObjectStruct, fyne.Window)
 
-var ImportantFields = map[string][]string{
-	"GEN": {
-		"Name",
-		"Description",
-	},
-	"PAC": {
-		"Alias",
-		"Description",
-		"Links",
-		"Categories",
-		"Owner",
-		"GU::Domain",
-		"Department",
-		"GU::Solution Classification",
-		"GU::Information Security Classification",
-		"Vendor",
-		"Supplier",
-		"Application Type",
-		"Operational Importance",
-		"Deployment Method",
-		"Build",
-
-		"Lifecycle Status",
-		"Internal Recommendation",
-		"Date of Last Release",
-		"Date of Next Release",
-		"Internal: In Development From",
-		"Internal: Live Date",
-		"Internal: Phase Out From",
-		"Internal: Retirement Date",
-		"Vendor: Contained From",
-		"Vendor: Out of Support",
-		"Update Schedule",
-		"Vendor Release Details",
-
-		"Standards Class",
-		"Standard Creation Date",
-		"Last Standard Review Date",
-		"Next Standard Review Date",
-		"Standard Retire Date",
-		"Approved Usage",
-		"Conditions & Restrictions",
-
-		"GU::Managed outside of DS",
-		"GU::Object Visibility",
-		"Serviceability characteristics",
-	},
-	"PTC": {
-		"Title",
-		"Description",
-		"GU::Information System Custodian",
-		"Supplier",
-		"Department",
-		"Owner",
-		"GU::Domain",
-		"GU::Information Security Classification",
-		"GU::Solution Classification",
-		"GU::Object Visibility",
-		"Lifecycle Status",
-		"Internal Recommendation",
-		"Operational Importance",
-		"Internal: In Development From",
-		"Internal: Live date",
-		"Internal: Phase Out From",
-		"Internal: Retirement date",
-	},
-}
+// Attributes fetched for each object type, by its short name; set from the edit forms
+var ImportantFields = map[string][]string{}
+
+// Object types FindMeThen looks through
+var SearchObjectTypes = []string{"Physical Application Component", "Physical Technology Component", "Logical Application Component"}
 
 // Simple find over iServer components, looking for the specified string
 // Focuses on PAC, PTC, and LAC
@@ -171,14 +134,16 @@ func (a *AzureAuth) FindMeThen(lookFor string, putInto laterLongUpdate, thenWind
 	for _, query := range []string{
 		strings.ReplaceAll(
 			fmt.Sprintf(
-				`$expand=ObjectType($select=Name),AttributeValues($select=StringValue,AttributeName;$filter=AttributeName in ('Alias'))&$filter=Model/Name eq 'Baseline Architecture' and ObjectType/Name in ('Physical Application Component','Physical Technology Component','Logical Application Component') and contains(Name, '%s')`,
+				`$expand=ObjectType($select=Name),AttributeValues($select=StringValue,AttributeName;$filter=AttributeName in ('Alias'))&$filter=Model/Name eq 'Baseline Architecture' and ObjectType/Name in ('%s') and contains(Name, '%s')`,
+				strings.Join(SearchObjectTypes, "','"),
 				lookFor,
 			),
 			" ",
 			"%20"),
 		strings.ReplaceAll(
 			fmt.Sprintf(
-				`$expand=ObjectType($select=Name),AttributeValues($select=StringValue,AttributeName;$filter=AttributeName in ('Alias'))&$filter=Model/Name eq 'Baseline Architecture' and ObjectType/Name in ('Physical Application Component','Physical Technology Component','Logical Application Component') and AttributeValues/OfficeArchitect.Contracts.OData.Model.AttributeValue.AttributeValueText/any(a:a/AttributeName in ('Alias','Description') and contains(a/Value,'%s'))`,
+				`$expand=ObjectType($select=Name),AttributeValues($select=StringValue,AttributeName;$filter=AttributeName in ('Alias'))&$filter=Model/Name eq 'Baseline Architecture' and ObjectType/Name in ('%s') and AttributeValues/OfficeArchitect.Contracts.OData.Model.AttributeValue.AttributeValueText/any(a:a/AttributeName in ('Alias','Description') and contains(a/Value,'%s'))`,
+				strings.Join(SearchObjectTypes, "','"),
 				lookFor,
 			),
 			" ",
@@ -242,83 +207,186 @@ func (a *AzureAuth) GetImportantFields(id, typeofobject string) IServerObjectStr
 	return toReturn
 }
 
+// An object with just the named attributes, for comparing against edits made outside the app
+func (a *AzureAuth) GetObjectAttributes(id string, names []string) (IServerObjectStruct, error) {
+	toReturn := IServerObjectStruct{}
+
+	query := `$expand=` + url.QueryEscape(`ObjectType($select=Name,ObjectTypeId),AttributeValues($select=StringValue,AttributeName,AttributeId;$filter=AttributeName in ("`+strings.Join(names, `","`)+`"))`)
+
+	path := fmt.Sprintf("/odata/Objects(%s)", id)
+	mep, err := a.CallRestEndpoint("GET", path, []byte{}, query)
+	if err != nil {
+		return toReturn, err
+	}
+	defer mep.Close()
+	bytemep, err := io.ReadAll(mep)
+	if err != nil {
+		return toReturn, err
+	}
+	err = json.Unmarshal(bytemep, &toReturn)
+	return toReturn, err
+}
+
+// When an object was last changed, and who by (their name where iServer has one), to tell if someone else has saved it
+func (a *AzureAuth) GetLastModified(id string) (string, string, error) {
+	toReturn := IServerObjectStruct{}
+	path := fmt.Sprintf("/odata/Objects(%s)", id)
+	mep, err := a.CallRestEndpoint("GET", path, []byte{}, "$select=LastModifiedDate,LastModifiedById&$expand=LastModifiedBy($select=FirstName,LastName,Email)")
+	if err != nil {
+		return "", "", err
+	}
+	defer mep.Close()
+	bytemep, err := io.ReadAll(mep)
+	if err != nil {
+		return "", "", err
+	}
+	err = json.Unmarshal(bytemep, &toReturn)
+	who := toReturn.LastModifiedBy.String()
+	if who == "" {
+		who = toReturn.LastModifiedById
+	}
+	return toReturn.LastModifiedDate, who, err
+}
+
+// A relationship's attributes, approval and who made and last changed it
+func (a *AzureAuth) GetRelationshipDetails(id string) (RelationshipStruct, error) {
+	toReturn := RelationshipStruct{}
+	path := fmt.Sprintf("/odata/Relationships(%s)", id)
+	query := strings.ReplaceAll(
+		`$select=RelationshipId,IsApproved,DateCreated,CreatedById,LastModifiedDate,LastModifiedById&$expand=RelationshipType($select=Name,LeadToMemberDirection,IsApprovable),AttributeValues($select=AttributeName,StringValue),CreatedBy($select=FirstName,LastName,Email),LastModifiedBy($select=FirstName,LastName,Email)`,
+		" ",
+		"%20")
+	mep, err := a.CallRestEndpoint("GET", path, []byte{}, query)
+	if err != nil {
+		return toReturn, err
+	}
+	defer mep.Close()
+	bytemep, err := io.ReadAll(mep)
+	if err != nil {
+		return toReturn, err
+	}
+	err = json.Unmarshal(bytemep, &toReturn)
+	return toReturn, err
+}
+
+// Save the given text attributes of a relationship and whether it's approved
+func (a *AzureAuth) SaveRelationshipDetails(id string, stringValues map[string]string, approved bool) (bool, string) {
+	saveValues := struct {
+		IsApproved      bool        `json:"IsApproved"`
+		AttributeValues []SaveValue `json:"AttributeValues,omitempty"`
+	}{IsApproved: approved}
+	for _, i := range sortedKeys(stringValues) {
+		saveValues.AttributeValues = append(saveValues.AttributeValues, SaveValue{
+			AttributeName:     i,
+			AttributeCategory: "Text",
+			TextValue:         stringValues[i],
+		})
+	}
+	x, err := json.Marshal(saveValues)
+	if err != nil {
+		return false, err.Error()
+	}
+	mep, err := a.CallRestEndpoint("PATCH", fmt.Sprintf("/odata/Relationships(%s)", id), x, "")
+	if err != nil {
+		return false, fmt.Sprintf("Error communicating with endpoint %s", err.Error())
+	}
+	defer mep.Close()
+	toReturn := struct {
+		Success  bool `json:"success"`
+		Messages []struct {
+			Message string `json:"message"`
+		} `json:"messages"`
+	}{}
+	bytemep, err := io.ReadAll(mep)
+	if err != nil {
+		return false, err.Error()
+	}
+	json.Unmarshal(bytemep, &toReturn)
+	returnMessages := []string{}
+	for _, x := range toReturn.Messages {
+		returnMessages = append(returnMessages, x.Message)
+	}
+	return toReturn.Success, strings.Join(returnMessages, "\n")
+}
+
+// Save the given attributes; any not given are left as they are
 func (a *AzureAuth) SaveObjectFields(
 	id string,
+	name string,
 	objectName string,
 	stringValues map[string]string,
 	selectValues map[string]string,
 	dateValues map[string]string,
 ) (bool, string, string) {
 	saveValues := SaveObject{}
-	saveValues.Name = stringValues["Title"]
+	saveValues.Name = name
 	saveValues.ModelId = BaselineArchitectureModel
-	switch objectName {
-	case "Physical Application Component":
-		saveValues.ObjectTypeId = "6fb624e4-b642-ea11-a601-28187852aafd"
-	case "Physical Technology Component":
-		saveValues.ObjectTypeId = "140714ec-b642-ea11-a601-28187852aafd"
-	case "Logical Application Component":
-		saveValues.ObjectTypeId = "7cb624e4-b642-ea11-a601-28187852aafd"
-	}
+	saveValues.ObjectTypeId = ObjectTypeIdFor(objectName)
 	// Name is special
-	saveValues.AttributeValues = append(saveValues.AttributeValues, SaveValue{
-		AttributeName:     "Name",
-		AttributeCategory: "Text",
-		TextValue:         stringValues["Title"],
-	})
-	delete(stringValues, "Title")
+	if _, ok := stringValues["Title"]; ok {
+		saveValues.AttributeValues = append(saveValues.AttributeValues, SaveValue{
+			AttributeName:     "Name",
+			AttributeCategory: "Text",
+			TextValue:         stringValues["Title"],
+		})
+		delete(stringValues, "Title")
+	}
 
 	// So is links
-	LinkValues := []ValuesValue{}
-	re := regexp.MustCompile(`^\s*(.*?)\s*\((.*)\)$\s*`)
-	stringValues["Links"] = strings.ReplaceAll(stringValues["Links"], "\n", ",")
-	for _, e := range strings.Split(stringValues["Links"], ",") {
-		bits := re.FindStringSubmatch(e)
-		if len(bits) > 2 {
-			LinkValues = append(LinkValues, ValuesValue{
-				Url:          bits[2],
-				DisplayValue: bits[1],
-			})
+	if _, ok := stringValues["Links"]; ok {
+		LinkValues := []ValuesValue{}
+		for _, e := range SplitLinks(stringValues["Links"]) {
+			bits := LinkPattern.FindStringSubmatch(e)
+			if len(bits) > 2 {
+				LinkValues = append(LinkValues, ValuesValue{
+					Url:          bits[2],
+					DisplayValue: bits[1],
+				})
+			}
 		}
+		saveValues.AttributeValues = append(saveValues.AttributeValues, SaveValue{
+			AttributeName:     "Links",
+			AttributeCategory: "Hyperlink",
+			Values:            LinkValues,
+		})
+		delete(stringValues, "Links")
 	}
-	saveValues.AttributeValues = append(saveValues.AttributeValues, SaveValue{
-		AttributeName:     "Links",
-		AttributeCategory: "Hyperlink",
-		Values:            LinkValues,
-	})
-	delete(stringValues, "Links")
 
 	// As is Categories
-	CategoryValues := []ValuesValue{}
-	for _, e := range strings.Split(selectValues["Categories"], ",") {
-		if len(e) > 0 {
-			CategoryValues = append(CategoryValues, ValuesValue{
-				Value:                          e,
-				AttributeConfigurationChoiceId: ValidChoices["Categories"][e],
-			})
+	if _, ok := selectValues["Categories"]; ok {
+		CategoryValues := []ValuesValue{}
+		for _, e := range strings.Split(selectValues["Categories"], ",") {
+			if len(e) > 0 {
+				CategoryValues = append(CategoryValues, ValuesValue{
+					Value:                          e,
+					AttributeConfigurationChoiceId: ValidChoices["Categories"][e],
+				})
+			}
 		}
+		saveValues.AttributeValues = append(saveValues.AttributeValues, SaveValue{
+			AttributeName:     "Categories",
+			AttributeCategory: "Choice",
+			ChoiceValues:      CategoryValues,
+		})
+		delete(selectValues, "Categories")
 	}
-	saveValues.AttributeValues = append(saveValues.AttributeValues, SaveValue{
-		AttributeName:     "Categories",
-		AttributeCategory: "Choice",
-		ChoiceValues:      CategoryValues,
-	})
-	delete(selectValues, "Categories")
 
 	// Generics
-	for i, e := range stringValues {
+	for _, i := range sortedKeys(stringValues) {
 		saveValues.AttributeValues = append(saveValues.AttributeValues, SaveValue{
 			AttributeName:     i,
 			AttributeCategory: "Text",
-			TextValue:         e,
+			TextValue:         stringValues[i],
 		})
 	}
-	saveValues.AttributeValues = append(saveValues.AttributeValues, SaveValue{
-		AttributeName:     "Owner",
-		AttributeCategory: "Text",
-		TextValue:         selectValues["Owner"],
-	})
-	delete(selectValues, "Owner")
+	if _, ok := selectValues["Owner"]; ok {
+		saveValues.AttributeValues = append(saveValues.AttributeValues, SaveValue{
+			AttributeName:     "Owner",
+			AttributeCategory: "Text",
+			TextValue:         selectValues["Owner"],
+		})
+		delete(selectValues, "Owner")
+	}
 	_, y := selectValues["GU::Managed outside of DS"]
 	if y {
 		saveValues.AttributeValues = append(saveValues.AttributeValues, SaveValue{
@@ -328,22 +396,63 @@ func (a *AzureAuth) SaveObjectFields(
 		})
 		delete(selectValues, "GU::Managed outside of DS")
 	}
-	for i, e := range selectValues {
+	for _, i := range sortedKeys(selectValues) {
+		choices := []ValuesValue{}
+		if selectValues[i] != "" {
+			choices = append(choices, ValuesValue{Value: selectValues[i], AttributeConfigurationChoiceId: ValidChoices[i][selectValues[i]]})
+		}
 		saveValues.AttributeValues = append(saveValues.AttributeValues, SaveValue{
 			AttributeName:     i,
 			AttributeCategory: "Choice",
-			ChoiceValues:      []ValuesValue{{Value: e, AttributeConfigurationChoiceId: ValidChoices[i][e]}},
+			ChoiceValues:      choices,
 		})
-
 	}
-	for i, e := range dateValues {
+	for _, i := range sortedKeys(dateValues) {
 		saveValues.AttributeValues = append(saveValues.AttributeValues, SaveValue{
 			AttributeName:     i,
 			AttributeCategory: "DateTime",
-			DateTimeValue:     e,
+			DateTimeValue:     dateValues[i],
+		})
+	}
+	return a.sendSaveObject(id, saveValues)
+}
+
+// Change only the given attributes of an existing object, leaving the others as they are
+func (a *AzureAuth) PatchObjectAttributes(id, name, objectType string, stringValues, selectValues map[string]string) (bool, string) {
+	saveValues := SaveObject{
+		Name:         name,
+		ObjectTypeId: ObjectTypeIdFor(objectType),
+		ModelId:      BaselineArchitectureModel,
+	}
+	for _, i := range sortedKeys(stringValues) {
+		saveValues.AttributeValues = append(saveValues.AttributeValues, SaveValue{
+			AttributeName:     i,
+			AttributeCategory: "Text",
+			TextValue:         stringValues[i],
+		})
+	}
+	for _, i := range sortedKeys(selectValues) {
+		saveValues.AttributeValues = append(saveValues.AttributeValues, SaveValue{
+			AttributeName:     i,
+			AttributeCategory: "Choice",
+			ChoiceValues:      []ValuesValue{{Value: selectValues[i], AttributeConfigurationChoiceId: ValidChoices[i][selectValues[i]]}},
 		})
+	}
+	success, message, _ := a.sendSaveObject(id, saveValues)
+	return success, message
+}
 
+func sortedKeys(me map[string]string) []string {
+	toReturn := []string{}
+	for i := range me {
+		toReturn = append(toReturn, i)
 	}
+	sort.Strings(toReturn)
+	return toReturn
+}
+
+// POST a new object, or PATCH an existing one
+func (a *AzureAuth) sendSaveObject(id string, saveValues SaveObject) (bool, string, string) {
 	x, err := json.Marshal(saveValues)
 	if err == nil {
 		var mep io.ReadCloser
@@ -403,7 +512,7 @@ func (a *AzureAuth) FindRelations(id string) []RelationStruct {
 
 	path := "/odata/Relationships"
 	query := fmt.Sprintf(
-		`includeIntersectional=false&%%24select=RelationshipId%%2CLeadObjectId%%2CMemberObjectId%%2CLeadObject%%2CMemberObject&%%24expand=RelationshipType(%%24select%%3DName%%2CLeadToMemberDirection)%%2CLeadObject(%%24select%%3DName%%2CObjectId%%2CObjectType%%3B%%24expand%%3DObjectType(%%24select%%3DName))%%2CMemberObject(%%24select%%3DName%%2CObjectId%%2CObjectType%%3B%%24expand%%3DObjectType(%%24select%%3DName))&%%24filter=LeadObjectId%%20eq%%20%s%%20or%%20MemberObjectId%%20eq%%20%s`,
+		`includeIntersectional=false&%%24select=RelationshipId%%2CRelationshipTypePairId%%2CLeadObjectId%%2CMemberObjectId%%2CLeadObject%%2CMemberObject&%%24expand=RelationshipType(%%24select%%3DName%%2CLeadToMemberDirection%%2CRelationshipTypeId)%%2CLeadObject(%%24select%%3DName%%2CObjectId%%2CObjectType%%3B%%24expand%%3DObjectType(%%24select%%3DName))%%2CMemberObject(%%24select%%3DName%%2CObjectId%%2CObjectType%%3B%%24expand%%3DObjectType(%%24select%%3DName))%%2CAttributeValues(%%24select%%3DAttributeName%%2CStringValue)&%%24filter=LeadObjectId%%20eq%%20%s%%20or%%20MemberObjectId%%20eq%%20%s`,
 		id,
 		id,
 	)
@@ -436,6 +545,27 @@ func (a *AzureAuth) FindRelations(id string) []RelationStruct {
 	return toReturn
 }
 
+// Fetch a single relationship, erroring if it no longer exists
+func (a *AzureAuth) GetRelation(id string) (RelationStruct, error) {
+	toReturn := RelationStruct{}
+	path := fmt.Sprintf("/odata/Relationships(%s)", id)
+	query := `%24select=RelationshipId%2CRelationshipTypePairId%2CLeadObjectId%2CMemberObjectId%2CLeadObject%2CMemberObject&%24expand=RelationshipType(%24select%3DName%2CLeadToMemberDirection%2CRelationshipTypeId)%2CLeadObject(%24select%3DName%2CObjectId%2CObjectType%3B%24expand%3DObjectType(%24select%3DName))%2CMemberObject(%24select%3DName%2CObjectId%2CObjectType%3B%24expand%3DObjectType(%24select%3DName))%2CAttributeValues(%24select%3DAttributeName%2CStringValue)`
+	mep, err := a.CallRestEndpoint("GET", path, []byte{}, query)
+	if err != nil {
+		return toReturn, err
+	}
+	defer mep.Close()
+	bytemep, err := io.ReadAll(mep)
+	if err != nil {
+		return toReturn, err
+	}
+	err = json.Unmarshal(bytemep, &toReturn)
+	if err == nil && toReturn.RelationshipId == "" {
+		err = fmt.Errorf("relationship %s %w", id, ErrNotFound)
+	}
+	return toReturn, err
+}
+
 func (a *AzureAuth) FindRelationsThen(id, typeofobject string, putInto laterRelationUpdate, thenWindow *fyne.Window) {
 	putInto(a.GetImportantFields(id, typeofobject), a.FindRelations(id), thenWindow)
 }
@@ -497,66 +627,79 @@ func (a *AzureAuth) GetProductManagersThen(department string, putInto laterStrin
 	putInto(toReturn, thenWindow)
 }
 
-func (a *AzureAuth) ReplaceProductManagers(original, newhotness string) (int, error) {
-	changed := 0
-	var err error
-	tochange := []string{}
+// Applications, logical and technology components whose Owner is exactly the given product manager
+func (a *AzureAuth) FindObjectsOwnedBy(owner string) ([]IServerObjectStruct, error) {
+	toReturn := []IServerObjectStruct{}
 
 	type objects struct {
 		Value    []IServerObjectStruct `json:"value"`
 		NextLink string                `json:"@odata.nextLink"`
 	}
 
-	// Get All To Change
 	path := "/odata/Objects"
 	query := fmt.Sprintf(
-		`$select=ObjectId&$filter=Model/Name eq 'Baseline Architecture' and ObjectType/Name in ('Physical Application Component','Logical Application Component','Physical Technology Component') and AttributeValues/OfficeArchitect.Contracts.OData.Model.AttributeValue.AttributeValueText/any(a:a/AttributeName eq 'Owner' and a/Value eq '%s')`,
-		original,
+		`$select=ObjectId,Name&$expand=ObjectType($select=Name,ObjectTypeId)&$filter=Model/Name eq 'Baseline Architecture' and ObjectType/Name in ('Physical Application Component','Logical Application Component','Physical Technology Component') and AttributeValues/OfficeArchitect.Contracts.OData.Model.AttributeValue.AttributeValueText/any(a:a/AttributeName eq 'Owner' and a/Value eq '%s')`,
+		strings.ReplaceAll(url.QueryEscape(strings.ReplaceAll(owner, "'", "''")), "+", " "),
 	)
 	query = strings.ReplaceAll(query, " ", "%20")
-	var mep io.ReadCloser
-	var bytemep []byte
 	for {
 		var oneCall objects
-		mep, err = a.CallRestEndpoint("GET", path, []byte{}, query)
+		mep, err := a.CallRestEndpoint("GET", path, []byte{}, query)
 		if err != nil {
-			break
+			return toReturn, err
 		}
 		defer mep.Close()
-		bytemep, err = io.ReadAll(mep)
-		json.Unmarshal(bytemep, &oneCall)
-
+		bytemep, err := io.ReadAll(mep)
 		if err != nil {
-			break
-		}
-		for _, x := range oneCall.Value {
-			tochange = append(tochange, x.ObjectId)
+			return toReturn, err
 		}
+		json.Unmarshal(bytemep, &oneCall)
+		toReturn = append(toReturn, oneCall.Value...)
 		if len(oneCall.NextLink) == 0 {
 			break
 		}
 		bits, err := url.Parse(oneCall.NextLink)
 		if err != nil {
-			break
+			return toReturn, err
 		}
 		path = bits.Path
 		query = bits.RawQuery
 		time.Sleep(100 * time.Millisecond)
 	}
+	return toReturn, nil
+}
 
-	if err == nil {
-		// Change 'empath := "/odata/Objects"
-		replaceBody := []byte(fmt.Sprintf(`{"AttributeValuesFlat": {"Owner":"%s"}}`, newhotness))
-		for _, y := range tochange {
-			_, err = a.CallRestEndpoint("PATCH", fmt.Sprintf(`/odata/Objects/%s`, y), replaceBody, ``)
-			if err != nil {
-				break
-			}
-			changed = changed + 1
-			time.Sleep(100 * time.Millisecond)
+// Set the Owner of one object
+func (a *AzureAuth) ReplaceProductManager(id, owner string) error {
+	replaceBody, err := json.Marshal(struct {
+		AttributeValuesFlat map[string]string `json:"AttributeValuesFlat"`
+	}{map[string]string{"Owner": owner}})
+	if err != nil {
+		return err
+	}
+	mep, err := a.CallRestEndpoint("PATCH", fmt.Sprintf(`/odata/Objects(%s)`, id), replaceBody, ``)
+	if err != nil {
+		return err
+	}
+	mep.Close()
+	return nil
+}
+
+// Set the Owner of each object in turn, telling progress how each one went
+func (a *AzureAuth) ReplaceProductManagers(ids []string, newOwner string, progress func(id string, err error)) (int, []error) {
+	changed := 0
+	errors := []error{}
+	for _, y := range ids {
+		err := a.ReplaceProductManager(y, newOwner)
+		progress(y, err)
+		if err != nil {
+			errors = append(errors, err)
+		} else {
+			changed++
 		}
+		time.Sleep(100 * time.Millisecond)
 	}
-	return changed, err
+	return changed, errors
 }
 
 func (a *AzureAuth) GetDomainThen(department string, putInto laterDomainOwned, thenWindow fyne.Window) {
@@ -756,138 +899,92 @@ func (a *AzureAuth) FindMeInTypeThen(
 	putInto(toReturn)
 }
 
-// EXCEL FUNCTIONS
+// Objects in the baseline model whose name or Alias is exactly the given text, ignoring case
+func (a *AzureAuth) FindObjectsByName(lookFor string) ([]FindStruct, error) {
+	toReturn := []FindStruct{}
 
-func (a *AzureAuth) GetRelationsAsSliceString(objectid, objecttype string) map[string][]string {
-	returns := map[string][]string{
-		"Capabilities": {},
-	}
-	for _, x := range a.FindRelations(objectid) {
-		target := x.MemberObject
-		if x.MemberObjectId == objectid {
-			target = x.LeadObject
-		}
-		returns[target.Type.Name] = append(returns[target.Type.Name], target.Name)
-	}
-	return returns
-}
-
-// Create the excel ProductManager overview report from iserver data
-func (a *AzureAuth) CreateProductManagerOverviewReport(department, savePath string) {
-	f := excelize.NewFile()
-
-	// Header style
-	style_header, err := f.NewStyle(&excelize.Style{
-		Border: []excelize.Border{
-			{Type: "bottom", Color: "000000", Style: 3},
-		},
-		Alignment: &excelize.Alignment{
-			Horizontal: "center",
-		},
-		Font: &excelize.Font{
-			Bold: true,
-			Size: 20,
-		},
-	})
-	if err != nil {
-		fmt.Println(err)
-	}
-
-	rowidx := 1
-	row := []interface{}{
-		"Object ID",
-		"Object Name",
-		"Object Type",
-		"Product Manager",
-		"Business Owner",
-		"Serviceability",
-		"Lifecycle",
-		"Capabilities",
-		"Physical Application Component",
-		"Physical Technology Component",
-		"Physical Data Component",
-	}
-	cell, err := excelize.CoordinatesToCellName(1, rowidx)
-	if err != nil {
-		fmt.Println(err)
-		return
-	}
-	f.SetSheetRow("Sheet1", cell, &row)
-	f.SetCellStyle("Sheet1", "A1", "K1", style_header)
-	f.SetColWidth("Sheet1", "B", "B", 62)
-	f.SetColWidth("Sheet1", "D", "D", 41.33)
-	f.SetColWidth("Sheet1", "E", "E", 62.17)
-	f.SetColWidth("Sheet1", "F", "F", 133.5)
-	f.SetColWidth("Sheet1", "G", "G", 17.17)
-	f.SetColWidth("Sheet1", "H", "H", 31.17)
-	f.SetColWidth("Sheet1", "I", "K", 52.17)
-	f.SetColVisible("Sheet1", "A", false)
-	f.SetColVisible("Sheet1", "C", false)
-
-	// Wrap style
-	style, err := f.NewStyle(&excelize.Style{
-		Alignment: &excelize.Alignment{
-			WrapText: true,
-		},
-	})
-	if err != nil {
-		fmt.Println(err)
+	type objects struct {
+		Value    []FindStruct `json:"value"`
+		NextLink string       `json:"@odata.nextLink"`
+	}
+
+	quoted := strings.ToLower(strings.ReplaceAll(lookFor, "'", "''"))
+	path := "/odata/Objects"
+	query := `$expand=ObjectType($select=Name),AttributeValues($select=StringValue,AttributeName;$filter=AttributeName in ('Alias'))&$filter=` +
+		strings.ReplaceAll(url.QueryEscape(fmt.Sprintf(
+			`Model/Name eq 'Baseline Architecture' and (tolower(Name) eq '%s' or AttributeValues/OfficeArchitect.Contracts.OData.Model.AttributeValue.AttributeValueText/any(a:a/AttributeName eq 'Alias' and tolower(a/Value) eq '%s'))`,
+			quoted,
+			quoted,
+		)), "+", "%20")
+	for {
+		var oneCall objects
+		mep, err := a.CallRestEndpoint("GET", path, []byte{}, query)
+		if err != nil {
+			return toReturn, err
+		}
+		bytemep, err := io.ReadAll(mep)
+		mep.Close()
+		if err != nil {
+			return toReturn, err
+		}
+		if err = json.Unmarshal(bytemep, &oneCall); err != nil {
+			return toReturn, err
+		}
+		toReturn = append(toReturn, oneCall.Value...)
+		if len(oneCall.NextLink) == 0 {
+			break
+		}
+		bits, err := url.Parse(oneCall.NextLink)
+		if err != nil {
+			log.Printf("Failed to parse next")
+			break
+		}
+		path = bits.Path
+		query = bits.RawQuery
+		time.Sleep(100 * time.Millisecond)
 	}
+	return toReturn, nil
+}
+
+// EXCEL FUNCTIONS
+
+// Objects of the given types in the domain with the named attributes, leaving out retired ones unless asked
+func (a *AzureAuth) GetDomainObjects(domain string, objectTypes, attributes []string, includeRetired bool) ([]IServerObjectStruct, error) {
+	toReturn := []IServerObjectStruct{}
 
 	type objects struct {
 		Value    []IServerObjectStruct `json:"value"`
 		NextLink string                `json:"@odata.nextLink"`
 	}
 
-	// Get all PAC and PTC by Product Manager
 	path := "/odata/Objects"
 	query := fmt.Sprintf(
-		`$expand=ObjectType($select=Name),AttributeValues($select=StringValue,AttributeName;$filter=AttributeName in ('Owner','Lifecycle Status','Serviceability characteristics','Department'))&$filter=Model/Name eq 'Baseline Architecture' and ObjectType/Name in ('Physical Application Component','Physical Technology Component') and AttributeValues/OfficeArchitect.Contracts.OData.Model.AttributeValue.AttributeValueChoice/any(a:a/AttributeName eq 'GU::Domain' and a/Values/any(b:indexof(b/Value,'%s') gt -1)) and AttributeValues/OfficeArchitect.Contracts.OData.Model.AttributeValue.AttributeValueChoice/any(a:a/AttributeName eq 'Lifecycle Status' and a/Values/all(b:indexof(b/Value,'Retired') eq -1))`,
-		department[1:6],
+		`$expand=ObjectType($select=Name,ObjectTypeId),AttributeValues($select=StringValue,AttributeName;$filter=AttributeName in ('%s'))`+
+			`&$filter=Model/Name eq '%s'`+
+			` and ObjectType/Name in ('%s')`+
+			` and AttributeValues/OfficeArchitect.Contracts.OData.Model.AttributeValue.AttributeValueChoice/any(a:a/AttributeName eq 'GU::Domain' and a/Values/any(b:b/Value eq '%s'))`,
+		strings.Join(attributes, "','"),
+		defaultModel,
+		strings.Join(objectTypes, "','"),
+		domainFilterValue(domain),
 	)
+	if !includeRetired {
+		query += ` and AttributeValues/OfficeArchitect.Contracts.OData.Model.AttributeValue.AttributeValueChoice/any(a:a/AttributeName eq 'Lifecycle Status' and a/Values/all(b:indexof(b/Value,'Retired') eq -1))`
+	}
 	query = strings.ReplaceAll(query, " ", "%20")
 	for {
 		var oneCall objects
 		mep, err := a.CallRestEndpoint("GET", path, []byte{}, query)
 		if err != nil {
-			log.Fatalf("failed to call endpoint %v\n", err)
+			return toReturn, err
 		}
 		defer mep.Close()
 		bytemep, err := io.ReadAll(mep)
-		json.Unmarshal(bytemep, &oneCall)
-
 		if err != nil {
-			log.Fatalf("failed to read io.Reader %v\n", err)
-		}
-		for _, x := range oneCall.Value {
-			fieldmap := map[string]interface{}{}
-			for _, y := range x.AttributeValues {
-				fieldmap[y.AttributeName] = y.StringValue
-			}
-			// Get all related Capabilities, PTC/PAC, Data items
-			rels := a.GetRelationsAsSliceString(x.ObjectId, x.ObjectType.Id)
-			// Cell
-			rowidx = rowidx + 1
-			row := []interface{}{
-				x.ObjectId,
-				x.Name,
-				x.ObjectType.Name,
-				fieldmap["Owner"],
-				fieldmap["Department"],
-				fieldmap["Serviceability characteristics"],
-				fieldmap["Lifecycle Status"],
-				strings.Join(rels["Capability"], "\r\n"),
-				strings.Join(rels["Physical Application Component"], "\r\n"),
-				strings.Join(rels["Physical Technology Component"], "\r\n"),
-				strings.Join(rels["Physical Data Component"], "\r\n"),
-			}
-			cell, err := excelize.CoordinatesToCellName(1, rowidx)
-			if err != nil {
-				fmt.Println(err)
-				return
-			}
-			f.SetSheetRow("Sheet1", cell, &row)
+			return toReturn, err
 		}
+		json.Unmarshal(bytemep, &oneCall)
+		toReturn = append(toReturn, oneCall.Value...)
 		if len(oneCall.NextLink) == 0 {
 			break
 		}
@@ -900,11 +997,5 @@ func (a *AzureAuth) CreateProductManagerOverviewReport(department, savePath stri
 		query = bits.RawQuery
 		time.Sleep(100 * time.Millisecond)
 	}
-	cell, _ = excelize.CoordinatesToCellName(11, rowidx)
-	f.SetCellStyle("Sheet1", "H2", cell, style)
-	f.AddTable("Sheet1", &excelize.Table{Range: "A1:" + cell})
-	// Export as an Excel report
-	if err := f.SaveAs(filepath.Join(savePath, "iServerAudit.xlsx")); err != nil {
-		fmt.Println(err)
-	}
+	return toReturn, nil
 }
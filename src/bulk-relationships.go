@@ -0,0 +1,298 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	azure "vonexplaino.com/m/v2/vondiagram/azure"
+)
+
+/**
+** Bulk relationships - relate the open object to many others of one type in
+** a single go, as either the source or the target of each. The relationship
+** type's pair is chosen by which end's object type leads it, relationships
+** that already exist are skipped, and each one's result is reported.
+**/
+
+var (
+	bulkIsSource = "Source"
+	bulkIsTarget = "Target"
+)
+
+type bulkRelation struct {
+	lead, member           azure.FindStruct
+	relationshipTypeId     string
+	relationshipTypePairId string
+	typeName               string
+	// Already in iServer, so not created again
+	exists bool
+	err    error
+}
+
+func (x bulkRelation) String() string {
+	return fmt.Sprintf("%s %s %s", x.lead.Name, x.typeName, x.member.Name)
+}
+
+// Every source related to every target, marking those iServer already has
+func planBulkRelations(
+	sources, targets []azure.FindStruct,
+	sourceTypeId, targetTypeId string,
+	relationshipType azure.RelationshipTypeStruct,
+	existing []azure.RelationStruct,
+) ([]bulkRelation, error) {
+	toReturn := []bulkRelation{}
+	pairId, sourceLeads, err := relationshipTypePair(relationshipType, sourceTypeId, targetTypeId)
+	if err != nil {
+		return toReturn, err
+	}
+	key := func(typeId, lead, member string) string { return typeId + "|" + lead + "|" + member }
+	already := map[string]bool{}
+	for _, x := range existing {
+		already[key(x.RelationshipType.RelationshipTypeId, x.LeadObjectId, x.MemberObjectId)] = true
+	}
+	planned := map[string]bool{}
+	for _, source := range sources {
+		for _, target := range targets {
+			x := bulkRelation{
+				lead:                   source,
+				member:                 target,
+				relationshipTypeId:     relationshipType.RelationshipTypeId,
+				relationshipTypePairId: pairId,
+				typeName:               relationshipType.Name,
+			}
+			if !sourceLeads {
+				x.lead, x.member = target, source
+			}
+			thisKey := key(x.relationshipTypeId, x.lead.ObjectId, x.member.ObjectId)
+			if planned[thisKey] {
+				continue
+			}
+			planned[thisKey] = true
+			x.exists = already[thisKey]
+			toReturn = append(toReturn, x)
+		}
+	}
+	sort.SliceStable(toReturn, func(i, j int) bool { return toReturn[i].String() < toReturn[j].String() })
+	return toReturn, nil
+}
+
+// Create each planned relationship that doesn't exist, keeping how each went
+func createBulkRelations(plan []bulkRelation, relate func(relationshipTypeId, relationshipTypePairId, leadObjectId, memberObjectId string) error) []bulkRelation {
+	toReturn := []bulkRelation{}
+	for _, x := range plan {
+		if !x.exists {
+			x.err = relate(x.relationshipTypeId, x.relationshipTypePairId, x.lead.ObjectId, x.member.ObjectId)
+		}
+		toReturn = append(toReturn, x)
+	}
+	return toReturn
+}
+
+func bulkPreview(plan []bulkRelation) string {
+	lines := []string{}
+	for _, x := range plan {
+		if x.exists {
+			lines = append(lines, "Skip, already exists: "+x.String())
+		} else {
+			lines = append(lines, "Create: "+x.String())
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func bulkResultSummary(results []bulkRelation) string {
+	created, skipped, failed := 0, 0, 0
+	lines := []string{}
+	for _, x := range results {
+		switch {
+		case x.exists:
+			skipped++
+			lines = append(lines, "Skipped: "+x.String())
+		case x.err != nil:
+			failed++
+			lines = append(lines, fmt.Sprintf("Failed: %s (%s)", x, x.err))
+		default:
+			created++
+			lines = append(lines, "Created: "+x.String())
+		}
+	}
+	return fmt.Sprintf("%d created, %d skipped, %d failed\n\n%s", created, skipped, failed, strings.Join(lines, "\n"))
+}
+
+// Search results by the label shown for them: the name, with the id added
+// where another result has the same name
+func bulkOptionLabels(finds []azure.FindStruct) map[string]azure.FindStruct {
+	named := map[string]int{}
+	for _, x := range finds {
+		named[x.Name]++
+	}
+	toReturn := map[string]azure.FindStruct{}
+	for _, x := range finds {
+		label := x.Name
+		if named[x.Name] > 1 {
+			label = fmt.Sprintf("%s (%s)", x.Name, x.ObjectId)
+		}
+		toReturn[label] = x
+	}
+	return toReturn
+}
+
+// Choose the other objects and the relationship type, preview, then create them all
+func showBulkRelationshipWindow(basics azure.IServerObjectStruct) {
+	bulkWindow := addWindowFor("Add Relationships: "+basics.Name, 700, 600)
+	thisObject := azure.FindStruct{Name: basics.Name, ObjectId: basics.ObjectId}
+	thisObject.Type.Name = basics.ObjectType.Name
+
+	var plan []bulkRelation
+	preview := widget.NewLabel("")
+	preview.Wrapping = fyne.TextWrapWord
+	create := widget.NewButton("Create relationships", func() {})
+	create.Disable()
+	clearPlan := func() {
+		plan = nil
+		preview.SetText("")
+		create.Disable()
+	}
+
+	role := widget.NewRadioGroup([]string{bulkIsSource, bulkIsTarget}, func(string) { clearPlan() })
+	role.Horizontal = true
+	role.Required = true
+	role.SetSelected(bulkIsSource)
+
+	relationshipTypes := map[string]azure.RelationshipTypeStruct{}
+	relationshipSelect := widget.NewSelect([]string{}, func(string) { clearPlan() })
+
+	chosen := map[string]azure.FindStruct{}
+	chosenLabel := widget.NewLabel("")
+	chosenLabel.Wrapping = fyne.TextWrapWord
+	showChosen := func() {
+		names := []string{}
+		for _, x := range chosen {
+			names = append(names, x.Name)
+		}
+		sort.Strings(names)
+		chosenLabel.SetText(fmt.Sprintf("%d chosen: %s", len(names), strings.Join(names, ", ")))
+		clearPlan()
+	}
+	found := map[string]azure.FindStruct{}
+	results := widget.NewCheckGroup([]string{}, func(selected []string) {
+		for label, x := range found {
+			delete(chosen, x.ObjectId)
+			if containsString(selected, label) {
+				chosen[x.ObjectId] = x
+			}
+		}
+		showChosen()
+	})
+
+	objectType := widget.NewSelect(relatableTypes(basics.ObjectType.Name), func(picked string) {
+		chosen = map[string]azure.FindStruct{}
+		found = map[string]azure.FindStruct{}
+		results.Options = []string{}
+		results.Selected = []string{}
+		results.Refresh()
+		showChosen()
+		relationshipTypes = map[string]azure.RelationshipTypeStruct{}
+		names := []string{}
+		for _, x := range relationshipTypesBetween(basics.ObjectType.Name, picked) {
+			relationshipTypes[x.Name] = x
+			names = append(names, x.Name)
+		}
+		sort.Strings(names)
+		relationshipSelect.ClearSelected()
+		relationshipSelect.SetOptions(names)
+	})
+
+	search := widget.NewEntry()
+	search.SetPlaceHolder("Name contains")
+	searchButton := widget.NewButton("Search", func() {
+		if objectType.Selected == "" {
+			dialog.ShowInformation("Add Relationships", "Choose an object type first", bulkWindow)
+			return
+		}
+		az.FindMeInTypeThen(search.Text, azure.ObjectTypeIdFor(objectType.Selected), func(finds []azure.FindStruct) {
+			found = bulkOptionLabels(finds)
+			labels := []string{}
+			selected := []string{}
+			for label, x := range found {
+				labels = append(labels, label)
+				if _, ok := chosen[x.ObjectId]; ok {
+					selected = append(selected, label)
+				}
+			}
+			sort.Strings(labels)
+			results.Options = labels
+			results.Selected = selected
+			results.Refresh()
+		})
+	})
+
+	previewButton := widget.NewButton("Preview", func() {
+		relationshipType, ok := relationshipTypes[relationshipSelect.Selected]
+		if !ok || len(chosen) == 0 {
+			dialog.ShowInformation("Add Relationships", "Choose a relationship and at least one object", bulkWindow)
+			return
+		}
+		others := []azure.FindStruct{}
+		for _, x := range chosen {
+			others = append(others, x)
+		}
+		sources, targets := []azure.FindStruct{thisObject}, others
+		sourceTypeId, targetTypeId := basics.ObjectType.Id, azure.ObjectTypeIdFor(objectType.Selected)
+		if role.Selected == bulkIsTarget {
+			sources, targets = targets, sources
+			sourceTypeId, targetTypeId = targetTypeId, sourceTypeId
+		}
+		var err error
+		plan, err = planBulkRelations(sources, targets, sourceTypeId, targetTypeId, relationshipType, az.FindRelations(basics.ObjectId))
+		if err != nil {
+			dialog.ShowError(err, bulkWindow)
+			return
+		}
+		preview.SetText(bulkPreview(plan))
+		create.Enable()
+	})
+
+	create.OnTapped = func() {
+		toCreate := 0
+		for _, x := range plan {
+			if !x.exists {
+				toCreate++
+			}
+		}
+		if toCreate == 0 {
+			dialog.ShowInformation("Add Relationships", "They all exist already", bulkWindow)
+			return
+		}
+		dialog.ShowConfirm("Add Relationships", fmt.Sprintf("Create %d relationships?", toCreate), func(ok bool) {
+			if !ok {
+				return
+			}
+			done := createBulkRelations(plan, az.CreateRelationship)
+			clearPlan()
+			dialog.ShowInformation("Add Relationships", bulkResultSummary(done), bulkWindow)
+		}, bulkWindow)
+	}
+
+	bulkWindow.SetContent(container.NewBorder(
+		widget.NewForm(
+			widget.NewFormItem(basics.Name+" is the", role),
+			widget.NewFormItem("Other object type", objectType),
+			widget.NewFormItem("Relationship", relationshipSelect),
+			widget.NewFormItem("Find", container.NewBorder(nil, nil, nil, searchButton, search)),
+		),
+		container.NewGridWithColumns(2, previewButton, create),
+		nil,
+		nil,
+		container.NewVSplit(
+			container.NewVScroll(container.NewVBox(results, chosenLabel)),
+			container.NewVScroll(preview),
+		),
+	))
+	bulkWindow.Show()
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	azure "vonexplaino.com/m/v2/vondiagram/azure"
+)
+
+func hostedOn() azure.RelationshipTypeStruct {
+	x := azure.RelationshipTypeStruct{RelationshipTypeId: "hosted", Name: "is hosted on"}
+	x.RelationshipTypePairs = append(x.RelationshipTypePairs, azure.RelationshipTypePair{
+		RelationshipTypePairId: "pac-ptc",
+		LeadObjectTypeId:       azure.ObjectTypeIdFor("Physical Application Component"),
+		MemberObjectTypeId:     azure.ObjectTypeIdFor("Physical Technology Component"),
+	})
+	return x
+}
+
+func findFor(id, name string) azure.FindStruct {
+	return azure.FindStruct{ObjectId: id, Name: name}
+}
+
+func TestPlanBulkRelations(t *testing.T) {
+	pac, ptc := azure.ObjectTypeIdFor("Physical Application Component"), azure.ObjectTypeIdFor("Physical Technology Component")
+	server := findFor("server", "Server")
+	apps := []azure.FindStruct{findFor("canvas", "Canvas"), findFor("moodle", "Moodle"), findFor("canvas", "Canvas")}
+	existing := relationFor("r1", "moodle", "server")
+	existing.RelationshipType.RelationshipTypeId = "hosted"
+
+	// Many sources and one target: the applications lead even though the server is the open object
+	plan, err := planBulkRelations(apps, []azure.FindStruct{server}, pac, ptc, hostedOn(), []azure.RelationStruct{existing})
+	assert.NoError(t, err)
+	assert.Equal(t, "Create: Canvas is hosted on Server\nSkip, already exists: Moodle is hosted on Server", bulkPreview(plan))
+
+	// One source and many targets, where the targets lead
+	plan, err = planBulkRelations([]azure.FindStruct{server}, apps, ptc, pac, hostedOn(), []azure.RelationStruct{existing})
+	assert.NoError(t, err)
+	assert.Len(t, plan, 2)
+	assert.Equal(t, "canvas", plan[0].lead.ObjectId)
+	assert.Equal(t, "server", plan[0].member.ObjectId)
+
+	related := []string{}
+	done := createBulkRelations(plan, func(relationshipTypeId, relationshipTypePairId, leadObjectId, memberObjectId string) error {
+		related = append(related, relationshipTypeId+" "+relationshipTypePairId+" "+leadObjectId+" "+memberObjectId)
+		return errors.New("denied")
+	})
+	assert.Equal(t, []string{"hosted pac-ptc canvas server"}, related)
+	assert.Equal(t, "0 created, 1 skipped, 1 failed\n\nFailed: Canvas is hosted on Server (denied)\nSkipped: Moodle is hosted on Server", bulkResultSummary(done))
+}
+
+func TestBulkOptionLabels(t *testing.T) {
+	labels := bulkOptionLabels([]azure.FindStruct{findFor("1", "Moodle"), findFor("2", "Moodle"), findFor("3", "Canvas")})
+	assert.Equal(t, map[string]azure.FindStruct{
+		"Moodle (1)": findFor("1", "Moodle"),
+		"Moodle (2)": findFor("2", "Moodle"),
+		"Canvas":     findFor("3", "Canvas"),
+	}, labels)
+}
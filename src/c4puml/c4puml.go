@@ -44,6 +44,12 @@ type Chart struct {
 	Containers    []Container
 	Relationships []Relationship
 	Layouts       []Layout
+	SelfContained bool
+}
+
+// The PlantUML for the relationship on its own
+func (x Relationship) String() string {
+	return relationshipAsString(x)
 }
 
 func relationshipAsString(x Relationship) string {
@@ -90,7 +96,7 @@ func NewChart() Chart {
 
 func (c *Chart) Draw() string {
 	toReturn := new(bytes.Buffer)
-	toReturn.WriteString("@startuml Solution Context\n!include https://raw.githubusercontent.com/colinmo/iserver-diagram/main/togaf/togaf-full.puml\n")
+	toReturn.WriteString(Header("Solution Context", c.SelfContained))
 	for _, ob := range c.Boundaries {
 		toReturn.WriteString(boundaryAsString(ob))
 	}
@@ -2,6 +2,7 @@ package c4puml
 
 import (
 	"log"
+	"strings"
 	"testing"
 )
 
@@ -123,3 +124,70 @@ func TestDraw(t *testing.T) {
 	x := d.Draw()
 	log.Print("\n" + x)
 }
+
+func TestDrawSelfContained(t *testing.T) {
+	d := NewChart()
+	d.SelfContained = true
+	d.Containers = []Container{{Alias: "pac1", Name: "PAC 1", TOGAF: "pac"}}
+	x := d.Draw()
+	if strings.Contains(x, "!include http") {
+		t.Errorf("self contained chart still has a remote include")
+	}
+	if !strings.Contains(x, "!include <C4/C4_Container>") {
+		t.Errorf("self contained chart is missing the local C4 include")
+	}
+	if !strings.Contains(x, "sprite $pac") {
+		t.Errorf("self contained chart is missing the TOGAF sprites")
+	}
+	if strings.Count(x, "@startuml") != 1 || strings.Count(x, "@enduml") != 1 {
+		t.Errorf("self contained chart should have exactly one start and end")
+	}
+}
+
+func TestParse(t *testing.T) {
+	chart := Parse(`@startuml Solution Context
+!include ./togaf/togaf.puml
+UpdateBoundaryStyle("pac", $bgColor=#65b5f6, $sprite="pac", $type="Physical application component")
+System_Boundary(ResearchManagementandApprovals,"Research Management and Approvals",$tags="pdc")
+System_Boundary(RIMSSolution, "Vendor Managed", $tags="pac") {
+    System_Boundary(Cloudflare,"Cloudflare DDoS Protection, Services",$tags="ptc")
+    Enterprise_Boundary(MicrosoftAzureVendor,"Microsoft Azure (Vendor)","location") {
+        System_Boundary(RIMSendpointIQ,"RIMS (endpoint IQ)",$tags="pac")
+    }
+}
+' Rel_L(Commented,Out,"")
+Person(pmgr,"Program Manager")
+Rel_D(RIMSendpointIQ,ResearchManagementandApprovals,"")
+Rel(pmgr,RIMSendpointIQ,"uses","HTTPS")
+Rel_Up(RIMSendpointIQ,Cloudflare,"is protected by")
+@enduml`)
+	elements := chart.Elements()
+	if len(elements) != 6 {
+		t.Fatalf("expected 6 elements, got %d %v", len(elements), elements)
+	}
+	names := map[string]Container{}
+	for _, x := range elements {
+		names[x.Alias] = x
+	}
+	if names["Cloudflare"].Name != "Cloudflare DDoS Protection, Services" || names["Cloudflare"].TOGAF != "ptc" {
+		t.Fatalf("quoted comma not kept %v", names["Cloudflare"])
+	}
+	if names["MicrosoftAzureVendor"].TOGAF != "location" {
+		t.Fatalf("enterprise boundary type not read %v", names["MicrosoftAzureVendor"])
+	}
+	if len(chart.Boundaries) != 1 || len(chart.Boundaries[0].Boundaries) != 1 || len(chart.Boundaries[0].Boundaries[0].Containers) != 1 {
+		t.Fatalf("nesting not kept %v", chart.Boundaries)
+	}
+	if len(chart.Relationships) != 3 {
+		t.Fatalf("expected 3 relationships, got %v", chart.Relationships)
+	}
+	if chart.Relationships[0].Direction != "Down" || chart.Relationships[0].To.Name != "Research Management and Approvals" {
+		t.Fatalf("short direction not expanded %v", chart.Relationships[0])
+	}
+	if chart.Relationships[1].Direction != "" || chart.Relationships[1].Label != "uses" || chart.Relationships[1].Technology != "HTTPS" {
+		t.Fatalf("plain Rel not read %v", chart.Relationships[1])
+	}
+	if chart.Relationships[2].Direction != "Up" || chart.Relationships[2].From.Name != "RIMS (endpoint IQ)" {
+		t.Fatalf("long direction not read %v", chart.Relationships[2])
+	}
+}
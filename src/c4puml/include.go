@@ -0,0 +1,54 @@
+//go:generate cp ../../togaf/togaf-full.puml togaf-full.puml
+
+package c4puml
+
+import (
+	_ "embed"
+	"strings"
+)
+
+/**
+** The TOGAF sprites and boundary styles normally come in through a remote
+** !include. A self-contained file inlines them instead, and points the C4
+** include at the stdlib bundled inside plantuml.jar, so the diagram renders
+** without network access.
+**/
+
+//go:embed togaf-full.puml
+var togafFull string
+
+var TogafInclude = "!include https://raw.githubusercontent.com/colinmo/iserver-diagram/main/togaf/togaf-full.puml\n"
+
+var c4RemoteInclude = "!include https://raw.githubusercontent.com/plantuml-stdlib/C4-PlantUML/master/C4_Container.puml"
+var c4LocalInclude = "!include <C4/C4_Container>"
+
+// Header returns the opening lines of a diagram, either including the TOGAF
+// definitions remotely or inlining them
+func Header(title string, selfContained bool) string {
+	toReturn := new(strings.Builder)
+	toReturn.WriteString("@startuml " + title + "\n")
+	if selfContained {
+		toReturn.WriteString(InlineTogaf())
+	} else {
+		toReturn.WriteString(TogafInclude)
+	}
+	return toReturn.String()
+}
+
+// InlineTogaf returns the embedded togaf-full.puml without its @startuml and
+// @enduml lines, with the C4 include switched to the local stdlib
+func InlineTogaf() string {
+	toReturn := new(strings.Builder)
+	for _, line := range strings.Split(strings.ReplaceAll(togafFull, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "@startuml"), strings.HasPrefix(trimmed, "@enduml"):
+			continue
+		case trimmed == c4RemoteInclude:
+			toReturn.WriteString(c4LocalInclude + "\n")
+		default:
+			toReturn.WriteString(line + "\n")
+		}
+	}
+	return toReturn.String()
+}
@@ -0,0 +1,176 @@
+package c4puml
+
+import (
+	"regexp"
+	"strings"
+)
+
+/**
+** Reads back the subset of C4-PlantUML this tool emits, plus what is
+** commonly hand written alongside it: System_Boundary, Enterprise_Boundary,
+** System, Person and Rel/Rel_* lines. Everything else is ignored.
+**/
+
+var elementLine = regexp.MustCompile(`^(System_Boundary|Enterprise_Boundary|System_Ext|System|Person_Ext|Person)\s*\((.*)\)\s*(\{)?\s*$`)
+var relationLine = regexp.MustCompile(`^Rel(?:_(Up|Down|Left|Right|U|D|L|R|Back|Neighbor))?\s*\((.*)\)\s*$`)
+
+var shortDirections = map[string]string{
+	"U": "Up",
+	"D": "Down",
+	"L": "Left",
+	"R": "Right",
+}
+
+// Parse reads a PlantUML document into a Chart. Boundaries opened with a
+// brace keep their children; elements without one become Containers.
+func Parse(puml string) Chart {
+	chart := NewChart()
+	aliases := map[string]Container{}
+	stack := []*Boundary{}
+	type pendingRelation struct {
+		from, to string
+		rel      Relationship
+	}
+	pending := []pendingRelation{}
+	for _, line := range strings.Split(strings.ReplaceAll(puml, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, "'") {
+			continue
+		}
+		if line == "}" {
+			if len(stack) > 0 {
+				closed := *stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				if len(stack) > 0 {
+					stack[len(stack)-1].Boundaries = append(stack[len(stack)-1].Boundaries, closed)
+				} else {
+					chart.Boundaries = append(chart.Boundaries, closed)
+				}
+			}
+			continue
+		}
+		if bits := elementLine.FindStringSubmatch(line); bits != nil {
+			args, named := splitArguments(bits[2])
+			if len(args) == 0 {
+				continue
+			}
+			element := Container{
+				Model:       bits[1],
+				Alias:       args[0],
+				TOGAF:       named["tags"],
+				Sprite:      named["sprite"],
+				Description: named["descr"],
+				External:    strings.HasSuffix(bits[1], "_Ext"),
+			}
+			if len(args) > 1 {
+				element.Name = args[1]
+			}
+			if len(element.TOGAF) == 0 && bits[1] == "Enterprise_Boundary" && len(args) > 2 {
+				element.TOGAF = args[2]
+			}
+			aliases[element.Alias] = element
+			if bits[3] == "{" {
+				stack = append(stack, &Boundary{
+					Model: element.Model,
+					Alias: element.Alias,
+					Name:  element.Name,
+					TOGAF: element.TOGAF,
+				})
+			} else if len(stack) > 0 {
+				stack[len(stack)-1].Containers = append(stack[len(stack)-1].Containers, element)
+			} else {
+				chart.Containers = append(chart.Containers, element)
+			}
+			continue
+		}
+		if bits := relationLine.FindStringSubmatch(line); bits != nil {
+			args, named := splitArguments(bits[2])
+			if len(args) < 2 {
+				continue
+			}
+			rel := Relationship{Direction: bits[1], Technology: named["techn"]}
+			if long, ok := shortDirections[rel.Direction]; ok {
+				rel.Direction = long
+			}
+			if len(args) > 2 {
+				rel.Label = args[2]
+			}
+			if len(args) > 3 && len(rel.Technology) == 0 {
+				rel.Technology = args[3]
+			}
+			pending = append(pending, pendingRelation{from: args[0], to: args[1], rel: rel})
+		}
+	}
+	// Unclosed boundaries still count
+	for len(stack) > 0 {
+		closed := *stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if len(stack) > 0 {
+			stack[len(stack)-1].Boundaries = append(stack[len(stack)-1].Boundaries, closed)
+		} else {
+			chart.Boundaries = append(chart.Boundaries, closed)
+		}
+	}
+	for _, x := range pending {
+		x.rel.From = aliasOrBare(aliases, x.from)
+		x.rel.To = aliasOrBare(aliases, x.to)
+		chart.Relationships = append(chart.Relationships, x.rel)
+	}
+	return chart
+}
+
+// Elements lists every boundary and container in the chart, outermost first
+func (c *Chart) Elements() []Container {
+	toReturn := []Container{}
+	var walk func(b Boundary)
+	walk = func(b Boundary) {
+		toReturn = append(toReturn, Container{Model: b.Model, Alias: b.Alias, Name: b.Name, TOGAF: b.TOGAF})
+		for _, x := range b.Boundaries {
+			walk(x)
+		}
+		toReturn = append(toReturn, b.Containers...)
+	}
+	for _, x := range c.Boundaries {
+		walk(x)
+	}
+	return append(toReturn, c.Containers...)
+}
+
+func aliasOrBare(aliases map[string]Container, alias string) Container {
+	if x, ok := aliases[alias]; ok {
+		return x
+	}
+	return Container{Alias: alias}
+}
+
+// Split a macro's arguments on commas outside quotes, separating $name=value pairs
+func splitArguments(raw string) ([]string, map[string]string) {
+	positional := []string{}
+	named := map[string]string{}
+	parts := []string{}
+	current := new(strings.Builder)
+	quoted := false
+	for _, r := range raw {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			current.WriteRune(r)
+		case r == ',' && !quoted:
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	parts = append(parts, current.String())
+	for _, x := range parts {
+		x = strings.TrimSpace(x)
+		if strings.HasPrefix(x, "$") && strings.Contains(x, "=") {
+			bits := strings.SplitN(x[1:], "=", 2)
+			named[strings.TrimSpace(bits[0])] = strings.Trim(strings.TrimSpace(bits[1]), `"`)
+			continue
+		}
+		positional = append(positional, strings.Trim(x, `"`))
+	}
+	return positional, named
+}
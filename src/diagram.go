@@ -0,0 +1,292 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	azure "vonexplaino.com/m/v2/vondiagram/azure"
+	"vonexplaino.com/m/v2/vondiagram/c4puml"
+)
+
+/**
+** Diagram - converts the found items into a C4 representation, and keeps a
+** definition of what was chosen beside the output so it can be regenerated
+**/
+
+var diagramDefinitionSuffix = ".diagram.json"
+
+var relationDirections = []string{"Up", "Down", "Left", "Right"}
+var layoutModes = []string{"TOP_DOWN", "LEFT_RIGHT", "LANDSCAPE"}
+
+type layoutHint struct {
+	From      string `json:"From"`
+	To        string `json:"To"`
+	Direction string `json:"Direction"`
+}
+
+type diagramDefinition struct {
+	RootObjectId    string            `json:"RootObjectId"`
+	RootName        string            `json:"RootName"`
+	RelationshipIds []string          `json:"RelationshipIds"`
+	Descriptions    map[string]string `json:"Descriptions"`
+	Directions      map[string]string `json:"Directions"`
+	Layouts         []layoutHint      `json:"Layouts"`
+	LayoutMode      string            `json:"LayoutMode"`
+	// Object types drawn as boundaries around what they relate to, outermost first
+	GroupTypes    []string `json:"GroupTypes"`
+	Formats       []string `json:"Formats"`
+	SelfContained bool     `json:"SelfContained"`
+}
+
+func newDiagramDefinition(basics azure.IServerObjectStruct) *diagramDefinition {
+	return &diagramDefinition{
+		RootObjectId:  basics.ObjectId,
+		RootName:      basics.Name,
+		Descriptions:  map[string]string{},
+		Directions:    map[string]string{},
+		Layouts:       []layoutHint{},
+		Formats:       []string{"puml"},
+		SelfContained: myApp.Preferences().BoolWithFallback("SelfContained", false),
+	}
+}
+
+// Rank each grouping type, defaulting to Locations for definitions saved before groups were configurable
+func (d diagramDefinition) groupRanks() map[string]int {
+	groups := d.GroupTypes
+	if len(groups) == 0 {
+		groups = []string{"Location"}
+	}
+	toReturn := map[string]int{}
+	for i, x := range groups {
+		toReturn[x] = i + 1
+	}
+	return toReturn
+}
+
+func relationDescription(x azure.RelationStruct) string {
+	return fmt.Sprintf("%s %s %s", x.LeadObject.Name, x.RelationshipType.LeadToMemberDirection, x.MemberObject.Name)
+}
+
+// Convert the selected relationships into a C4 PlantUML diagram centred on the base object
+func diagramPlantUML(basics azure.IServerObjectStruct, selectedRelations map[string]azure.RelationStruct, hints diagramDefinition) string {
+	toReturn := new(strings.Builder)
+	toReturn.WriteString(c4puml.Header("Solution Context", hints.SelfContained))
+	if len(hints.LayoutMode) > 0 {
+		toReturn.WriteString(fmt.Sprintf("LAYOUT_%s()\n", hints.LayoutMode))
+	}
+	alreadyDrawn := map[string]string{}
+	alreadyDrawn = map[string]string{
+		basics.ObjectId: nameToToken(&alreadyDrawn, basics.Name),
+	}
+	relationships := map[string]relationshipStruct{}
+	objects := map[string]objectStruct{}
+	addToObjectStruct(&objects, alreadyDrawn[basics.ObjectId], basics.Name, "physical application component")
+	for _, x := range sortedRelations(selectedRelations) {
+		leftAlias := ""
+		rightAlias := ""
+		var y bool
+		if leftAlias, y = alreadyDrawn[x.LeadObjectId]; !y {
+			leftAlias = nameToToken(&alreadyDrawn, x.LeadObject.Name)
+			alreadyDrawn[x.LeadObjectId] = leftAlias
+			addToObjectStruct(&objects, alreadyDrawn[x.LeadObjectId], x.LeadObject.Name, x.LeadObject.Type.Name)
+		}
+		if rightAlias, y = alreadyDrawn[x.MemberObjectId]; !y {
+			rightAlias = nameToToken(&alreadyDrawn, x.MemberObject.Name)
+			alreadyDrawn[x.MemberObjectId] = rightAlias
+			addToObjectStruct(&objects, alreadyDrawn[x.MemberObjectId], x.MemberObject.Name, x.MemberObject.Type.Name)
+		}
+		addRelationship(
+			&relationships,
+			&objects,
+			leftAlias,
+			x.LeadObject,
+			rightAlias,
+			x.MemberObject,
+			x.RelationshipId,
+			relationLabel(x),
+			relationAttribute(x, relationshipTechnology),
+			hints.groupRanks(),
+		)
+	}
+	for _, x := range objects {
+		toReturn.WriteString(drawObject(x))
+	}
+	for i, x := range relationships {
+		toReturn.WriteString(c4puml.Relationship{
+			From:       c4puml.Container{Alias: x.leftAlias},
+			To:         c4puml.Container{Alias: x.rightAlias},
+			Label:      x.relationshipName,
+			Technology: x.technology,
+			Direction:  hints.Directions[i],
+		}.String())
+	}
+	for _, x := range hints.Layouts {
+		from, fromOk := alreadyDrawn[x.From]
+		to, toOk := alreadyDrawn[x.To]
+		if fromOk && toOk {
+			toReturn.WriteString(fmt.Sprintf("Lay_%s(%s,%s)\n", x.Direction, from, to))
+		}
+	}
+	toReturn.WriteString(PlantUMLEnd)
+	return toReturn.String()
+}
+
+// Write the diagram in each requested format plus its definition, returning the files written
+func saveDiagram(fileBase string, basics azure.IServerObjectStruct, selectedRelations map[string]azure.RelationStruct, definition diagramDefinition) ([]string, error) {
+	definition.RelationshipIds = []string{}
+	definition.Descriptions = map[string]string{}
+	for _, x := range sortedRelations(selectedRelations) {
+		definition.RelationshipIds = append(definition.RelationshipIds, x.RelationshipId)
+		definition.Descriptions[x.RelationshipId] = relationDescription(x)
+	}
+	written, err := writeDiagramOutputs(fileBase, diagramPlantUML(basics, selectedRelations, definition), definition.Formats)
+	if err != nil {
+		return written, err
+	}
+	asJson, err := json.MarshalIndent(definition, "", "    ")
+	if err != nil {
+		return written, err
+	}
+	if err = os.WriteFile(fileBase+diagramDefinitionSuffix, asJson, 0644); err != nil {
+		return written, err
+	}
+	return append(written, fileBase+diagramDefinitionSuffix), nil
+}
+
+func writeDiagramOutputs(fileBase, puml string, formats []string) ([]string, error) {
+	written := []string{}
+	for _, format := range formats {
+		fileName := fileBase + "." + format
+		switch format {
+		case "puml":
+			if err := os.WriteFile(fileName, []byte(puml), 0644); err != nil {
+				return written, err
+			}
+		case "png", "svg":
+			jar := myApp.Preferences().StringWithFallback("PlantUMLJar", "")
+			if jar == "" {
+				return written, fmt.Errorf("a plantuml.jar must be set in Settings to save %s", format)
+			}
+			rendered, err := renderPlantUML(jar, puml, format)
+			if err != nil {
+				return written, err
+			}
+			if err = os.WriteFile(fileName, rendered, 0644); err != nil {
+				return written, err
+			}
+		default:
+			return written, fmt.Errorf("unknown diagram format %s", format)
+		}
+		written = append(written, fileName)
+	}
+	return written, nil
+}
+
+func loadDiagramDefinition(fileName string) (diagramDefinition, error) {
+	definition := diagramDefinition{}
+	contents, err := os.ReadFile(fileName)
+	if err != nil {
+		return definition, err
+	}
+	err = json.Unmarshal(contents, &definition)
+	if definition.Directions == nil {
+		definition.Directions = map[string]string{}
+	}
+	if definition.Descriptions == nil {
+		definition.Descriptions = map[string]string{}
+	}
+	return definition, err
+}
+
+// Re-query iServer for every saved relationship and rebuild the outputs next to the definition.
+// Relationships that no longer exist are left out of the diagram and returned for reporting;
+// any other failure to fetch one stops before the existing outputs are overwritten.
+func regenerateDiagram(definitionFile string, fetch func(string) (azure.RelationStruct, error)) ([]string, []string, error) {
+	definition, err := loadDiagramDefinition(definitionFile)
+	if err != nil {
+		return []string{}, []string{}, err
+	}
+	missing := []string{}
+	selectedRelations := map[string]azure.RelationStruct{}
+	basics := azure.IServerObjectStruct{ObjectId: definition.RootObjectId, Name: definition.RootName}
+	for _, id := range definition.RelationshipIds {
+		x, err := fetch(id)
+		if err != nil {
+			description := definition.Descriptions[id]
+			if description == "" {
+				description = id
+			}
+			if !errors.Is(err, azure.ErrNotFound) {
+				return []string{}, []string{}, fmt.Errorf("could not fetch %s: %w", description, err)
+			}
+			missing = append(missing, description)
+			continue
+		}
+		selectedRelations[id] = x
+		if x.LeadObjectId == basics.ObjectId {
+			basics.Name = x.LeadObject.Name
+		} else if x.MemberObjectId == basics.ObjectId {
+			basics.Name = x.MemberObject.Name
+		}
+	}
+	sort.Strings(missing)
+	fileBase := strings.TrimSuffix(definitionFile, diagramDefinitionSuffix)
+	puml := diagramPlantUML(basics, selectedRelations, definition)
+	if len(missing) > 0 {
+		puml = strings.Replace(puml, PlantUMLEnd, "' No longer in iServer:\n' "+strings.Join(missing, "\n' ")+"\n"+PlantUMLEnd, 1)
+	}
+	written, err := writeDiagramOutputs(fileBase, puml, definition.Formats)
+	return written, missing, err
+}
+
+// Pick a saved definition and rebuild its outputs, reporting anything that has gone from iServer
+func showRegenerateDialog(thenWindow fyne.Window) {
+	dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, thenWindow)
+			return
+		}
+		if reader == nil {
+			return
+		}
+		reader.Close()
+		UpdateMessage("Regenerating")
+		written, missing, err := regenerateDiagram(reader.URI().Path(), az.GetRelation)
+		UpdateMessage("Ready")
+		if err != nil {
+			dialog.ShowError(err, thenWindow)
+			return
+		}
+		message := fmt.Sprintf("Wrote\n%s", strings.Join(written, "\n"))
+		if len(missing) > 0 {
+			message += fmt.Sprintf("\n\nNo longer in iServer\n%s", strings.Join(missing, "\n"))
+		}
+		dialog.ShowInformation("Regenerated", message, thenWindow)
+	}, thenWindow)
+}
+
+// Command line `regenerate <definition.diagram.json>...`, for refreshing documents without the GUI
+func runRegenerateCLI(definitionFiles []string) int {
+	az.StartAzure()
+	failed := 0
+	for _, x := range definitionFiles {
+		written, missing, err := regenerateDiagram(filepath.Clean(x), az.GetRelation)
+		if err != nil {
+			fmt.Printf("%s: %v\n", x, err)
+			failed++
+			continue
+		}
+		fmt.Printf("%s: wrote %s\n", x, strings.Join(written, ", "))
+		for _, m := range missing {
+			fmt.Printf("  no longer exists: %s\n", m)
+		}
+	}
+	return failed
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	azure "vonexplaino.com/m/v2/vondiagram/azure"
+)
+
+func TestDiagramPlantUMLHints(t *testing.T) {
+	basics := azure.IServerObjectStruct{ObjectId: "1", Name: "Root"}
+	root := azure.FindStruct{ObjectId: "1", Name: "Root"}
+	root.Type.Name = "Physical Application Component"
+	other := azure.FindStruct{ObjectId: "2", Name: "Other"}
+	other.Type.Name = "Physical Technology Component"
+	selected := map[string]azure.RelationStruct{
+		"r1": {
+			RelationshipId: "r1",
+			LeadObjectId:   "1",
+			LeadObject:     root,
+			MemberObjectId: "2",
+			MemberObject:   other,
+		},
+	}
+	hints := diagramDefinition{
+		Directions: map[string]string{"r1": "Left"},
+		Layouts:    []layoutHint{{From: "1", To: "2", Direction: "Down"}, {From: "1", To: "missing", Direction: "Up"}},
+		LayoutMode: "LEFT_RIGHT",
+	}
+	puml := diagramPlantUML(basics, selected, hints)
+	assert.Contains(t, puml, "LAYOUT_LEFT_RIGHT()\n")
+	assert.Contains(t, puml, "Rel_Left(Root,Other,")
+	assert.Contains(t, puml, "Lay_Down(Root,Other)\n")
+	assert.NotContains(t, puml, "Lay_Up")
+}
+
+func TestRegenerateDiagram(t *testing.T) {
+	definitionFile := filepath.Join(t.TempDir(), "canvas"+diagramDefinitionSuffix)
+	assert.NoError(t, os.WriteFile(definitionFile, []byte(`{
+		"RootObjectId": "A",
+		"RootName": "A",
+		"RelationshipIds": ["r1", "r2"],
+		"Descriptions": {"r2": "A is hosted on Gone"},
+		"Formats": ["puml"]
+	}`), 0644))
+	pumlFile := filepath.Join(filepath.Dir(definitionFile), "canvas.puml")
+	assert.NoError(t, os.WriteFile(pumlFile, []byte("before"), 0644))
+
+	failing := func(id string) (azure.RelationStruct, error) {
+		return azure.RelationStruct{}, errors.New("iserver query failure, received 401")
+	}
+	_, _, err := regenerateDiagram(definitionFile, failing)
+	assert.Error(t, err)
+	contents, _ := os.ReadFile(pumlFile)
+	assert.Equal(t, "before", string(contents), "a failed fetch must not overwrite the outputs")
+
+	oneGone := func(id string) (azure.RelationStruct, error) {
+		if id == "r2" {
+			return azure.RelationStruct{}, azure.ErrNotFound
+		}
+		return testRelation("r1", "A", "Physical Application Component", "uses", "B", "Physical Application Component"), nil
+	}
+	written, missing, err := regenerateDiagram(definitionFile, oneGone)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{pumlFile}, written)
+	assert.Equal(t, []string{"A is hosted on Gone"}, missing)
+	contents, _ = os.ReadFile(pumlFile)
+	assert.Contains(t, string(contents), "' No longer in iServer:\n' A is hosted on Gone\n")
+}
+
+func TestDiagramObjectLabels(t *testing.T) {
+	pac := "Physical Application Component"
+	ptc := "Physical Technology Component"
+	basics := azure.IServerObjectStruct{ObjectId: "A", Name: "Moodle"}
+	basics.ObjectType.Name = pac
+	selected := map[string]azure.RelationStruct{
+		"r1": testRelation("r1", "A", pac, "is hosted on", "B", ptc),
+		"r2": testRelation("r2", "A", pac, "uses", "C", pac),
+		"r3": testRelation("r3", "A", pac, "uses", "D", pac),
+	}
+	// Named alike: the same name on a different type, and the same name and type
+	for id, name := range map[string]string{"r1": "Moodle", "r2": "Oracle", "r3": "Oracle"} {
+		x := selected[id]
+		x.MemberObject.Name = name
+		selected[id] = x
+	}
+	labels, sorted := diagramObjectLabels(basics, selected)
+	assert.Equal(t, map[string]string{
+		"A": "Moodle (" + pac + ")",
+		"B": "Moodle (" + ptc + ")",
+		"C": "Oracle (C)",
+		"D": "Oracle (D)",
+	}, labels)
+	assert.Len(t, sorted, 4)
+}
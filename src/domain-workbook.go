@@ -0,0 +1,385 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"github.com/xuri/excelize/v2"
+	azure "vonexplaino.com/m/v2/vondiagram/azure"
+)
+
+/**
+** Domain workbook - the Excel audit of a domain's applications, technology
+** and logical components, with their relationships, the capabilities they
+** support and the gaps in their data. Column A of each sheet holds the
+** iServer id so the workbook can be read back in.
+**/
+
+type auditColumn struct {
+	Header    string
+	Attribute string
+	Width     float64
+	// How iServer saves the attribute, Text or Choice
+	Category string
+}
+
+// The editable attribute columns of the object sheets, after the id and name
+var auditColumns = []auditColumn{
+	{"Product Manager", "Owner", 30, "Text"},
+	{"Business Owner", "Department", 40, "Text"},
+	{"Serviceability", "Serviceability characteristics", 60, "Text"},
+	{"Lifecycle", "Lifecycle Status", 16, "Choice"},
+}
+
+var auditSheets = []struct {
+	Sheet      string
+	ObjectType string
+}{
+	{"Applications", "Physical Application Component"},
+	{"Technology", "Physical Technology Component"},
+	{"Logical", "Logical Application Component"},
+}
+
+// Related object types listed against each object
+var auditRelatedTypes = []string{
+	"Capability",
+	"Logical Application Component",
+	"Physical Application Component",
+	"Physical Technology Component",
+	"Physical Data Component",
+}
+
+var lifecycleFills = map[string]string{
+	"Proposed":       "D9D9D9",
+	"In Development": "8EA9DB",
+	"Live":           "63BE7B",
+	"Phasing Out":    "FFEB84",
+	"Retired":        "F8696B",
+}
+
+type auditObject struct {
+	azure.IServerObjectStruct
+	fields  map[string]string
+	related map[string][]azure.FindStruct
+}
+
+// A cell showing an object's name, linked to its iServer page
+type auditLink struct {
+	Name     string
+	ObjectId string
+}
+
+type auditSheet struct {
+	name    string
+	headers []string
+	widths  []float64
+	rows    [][]interface{}
+	// 1 based column of Lifecycle values to colour, 0 for none
+	lifecycle int
+}
+
+// Gather each object's attributes and related objects, and every relationship seen once
+func buildAuditObjects(objects []azure.IServerObjectStruct, relationsFor func(string) []azure.RelationStruct) ([]auditObject, []azure.RelationStruct) {
+	toReturn := []auditObject{}
+	relations := []azure.RelationStruct{}
+	seen := map[string]bool{}
+	for _, x := range objects {
+		row := auditObject{IServerObjectStruct: x, fields: map[string]string{}, related: map[string][]azure.FindStruct{}}
+		for _, y := range x.AttributeValues {
+			row.fields[y.AttributeName] = y.StringValue
+		}
+		for _, y := range relationsFor(x.ObjectId) {
+			target, targetId := y.MemberObject, y.MemberObjectId
+			if y.MemberObjectId == x.ObjectId {
+				target, targetId = y.LeadObject, y.LeadObjectId
+			}
+			target.ObjectId = targetId
+			row.related[target.Type.Name] = append(row.related[target.Type.Name], target)
+			if !seen[y.RelationshipId] {
+				seen[y.RelationshipId] = true
+				relations = append(relations, y)
+			}
+		}
+		toReturn = append(toReturn, row)
+	}
+	sort.Slice(toReturn, func(i, j int) bool { return toReturn[i].Name < toReturn[j].Name })
+	return toReturn, relations
+}
+
+func auditObjectSheet(name, objectType string, objects []auditObject) auditSheet {
+	sheet := auditSheet{
+		name:    name,
+		headers: []string{"Object ID", "Name"},
+		widths:  []float64{38, 50},
+	}
+	for i, x := range auditColumns {
+		sheet.headers = append(sheet.headers, x.Header)
+		sheet.widths = append(sheet.widths, x.Width)
+		if x.Attribute == "Lifecycle Status" {
+			sheet.lifecycle = i + 3
+		}
+	}
+	for _, x := range auditRelatedTypes {
+		if x == objectType {
+			x = "Related " + x
+		}
+		sheet.headers = append(sheet.headers, x)
+		sheet.widths = append(sheet.widths, 45)
+	}
+	for _, x := range objects {
+		if x.ObjectType.Name != objectType {
+			continue
+		}
+		row := []interface{}{x.ObjectId, auditLink{x.Name, x.ObjectId}}
+		for _, y := range auditColumns {
+			row = append(row, x.fields[y.Attribute])
+		}
+		for _, y := range auditRelatedTypes {
+			names := []string{}
+			for _, z := range x.related[y] {
+				names = append(names, z.Name)
+			}
+			sort.Strings(names)
+			row = append(row, strings.Join(names, "\n"))
+		}
+		sheet.rows = append(sheet.rows, row)
+	}
+	return sheet
+}
+
+func auditRelationshipSheet(relations []azure.RelationStruct) auditSheet {
+	sheet := auditSheet{
+		name:    "Relationships",
+		headers: []string{"Relationship ID", "From", "From type", "Relationship", "To", "To type"},
+		widths:  []float64{38, 50, 30, 30, 50, 30},
+	}
+	for _, x := range relations {
+		sheet.rows = append(sheet.rows, []interface{}{
+			x.RelationshipId,
+			auditLink{x.LeadObject.Name, x.LeadObjectId},
+			x.LeadObject.Type.Name,
+			relationName(x),
+			auditLink{x.MemberObject.Name, x.MemberObjectId},
+			x.MemberObject.Type.Name,
+		})
+	}
+	sort.SliceStable(sheet.rows, func(i, j int) bool {
+		return sheet.rows[i][1].(auditLink).Name < sheet.rows[j][1].(auditLink).Name
+	})
+	return sheet
+}
+
+func relationName(x azure.RelationStruct) string {
+	if len(x.RelationshipType.LeadToMemberDirection) > 0 {
+		return x.RelationshipType.LeadToMemberDirection
+	}
+	return x.RelationshipType.Name
+}
+
+// Each capability the domain's objects link to, and what supports it
+func auditCapabilitySheet(objects []auditObject) auditSheet {
+	sheet := auditSheet{
+		name:    "Capabilities",
+		headers: []string{"Object ID", "Capability"},
+		widths:  []float64{38, 50},
+	}
+	capabilities := map[string]azure.FindStruct{}
+	supporters := map[string]map[string][]string{}
+	for _, x := range objects {
+		for _, y := range x.related["Capability"] {
+			capabilities[y.ObjectId] = y
+			if _, ok := supporters[y.ObjectId]; !ok {
+				supporters[y.ObjectId] = map[string][]string{}
+			}
+			supporters[y.ObjectId][x.ObjectType.Name] = append(supporters[y.ObjectId][x.ObjectType.Name], x.Name)
+		}
+	}
+	for _, x := range auditSheets {
+		sheet.headers = append(sheet.headers, x.Sheet+" count", x.Sheet)
+		sheet.widths = append(sheet.widths, 12, 45)
+	}
+	ids := []string{}
+	for id := range capabilities {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return capabilities[ids[i]].Name < capabilities[ids[j]].Name })
+	for _, id := range ids {
+		row := []interface{}{id, auditLink{capabilities[id].Name, id}}
+		for _, x := range auditSheets {
+			names := supporters[id][x.ObjectType]
+			sort.Strings(names)
+			row = append(row, len(names), strings.Join(names, "\n"))
+		}
+		sheet.rows = append(sheet.rows, row)
+	}
+	return sheet
+}
+
+func auditQualitySheet(issues []qualityIssue) auditSheet {
+	sheet := auditSheet{
+		name:    "Data Quality",
+		headers: []string{"Object ID", "Name", "Type", "Severity", "Rule", "Issue"},
+		widths:  []float64{38, 50, 30, 12, 30, 60},
+	}
+	for _, x := range issues {
+		sheet.rows = append(sheet.rows, []interface{}{
+			x.Object.ObjectId,
+			auditLink{x.Object.Name, x.Object.ObjectId},
+			x.Object.ObjectType.Name,
+			x.Rule.Severity,
+			x.Rule.Name,
+			x.Message,
+		})
+	}
+	return sheet
+}
+
+var fileNameNoise = regexp.MustCompile(`[^A-Za-z0-9 _-]+`)
+
+func domainWorkbookName(domain string, when time.Time) string {
+	return fmt.Sprintf("iServer Audit %s %s.xlsx", strings.TrimSpace(fileNameNoise.ReplaceAllString(domain, "")), when.Format("2006-01-02"))
+}
+
+func createDomainWorkbook(objects []auditObject, relations []azure.RelationStruct, issues []qualityIssue, fileName string) error {
+	f := excelize.NewFile()
+	defer f.Close()
+	header, err := f.NewStyle(&excelize.Style{
+		Border: []excelize.Border{{Type: "bottom", Color: "000000", Style: 3}},
+		Font:   &excelize.Font{Bold: true},
+	})
+	if err != nil {
+		return err
+	}
+	wrap, err := f.NewStyle(&excelize.Style{Alignment: &excelize.Alignment{WrapText: true, Vertical: "top"}})
+	if err != nil {
+		return err
+	}
+	link, err := f.NewStyle(&excelize.Style{
+		Alignment: &excelize.Alignment{Vertical: "top"},
+		Font:      &excelize.Font{Color: "0563C1", Underline: "single"},
+	})
+	if err != nil {
+		return err
+	}
+	lifecycles := []excelize.ConditionalFormatOptions{}
+	for _, x := range getMapStringKeys(lifecycleFills) {
+		style, err := f.NewConditionalStyle(&excelize.Style{
+			Fill: excelize.Fill{Type: "pattern", Color: []string{lifecycleFills[x]}, Pattern: 1},
+		})
+		if err != nil {
+			return err
+		}
+		lifecycles = append(lifecycles, excelize.ConditionalFormatOptions{Type: "cell", Criteria: "==", Format: style, Value: `"` + x + `"`})
+	}
+
+	sheets := []auditSheet{}
+	for _, x := range auditSheets {
+		sheets = append(sheets, auditObjectSheet(x.Sheet, x.ObjectType, objects))
+	}
+	sheets = append(sheets, auditRelationshipSheet(relations), auditCapabilitySheet(objects), auditQualitySheet(issues))
+
+	for i, sheet := range sheets {
+		if i == 0 {
+			f.SetSheetName("Sheet1", sheet.name)
+		} else if _, err := f.NewSheet(sheet.name); err != nil {
+			return err
+		}
+		lastColumn, _ := excelize.ColumnNumberToName(len(sheet.headers))
+		f.SetSheetRow(sheet.name, "A1", &sheet.headers)
+		f.SetCellStyle(sheet.name, "A1", lastColumn+"1", header)
+		for j, width := range sheet.widths {
+			column, _ := excelize.ColumnNumberToName(j + 1)
+			f.SetColWidth(sheet.name, column, column, width)
+		}
+		f.SetColVisible(sheet.name, "A", false)
+		for j, row := range sheet.rows {
+			for k, value := range row {
+				cell, _ := excelize.CoordinatesToCellName(k+1, j+2)
+				if x, ok := value.(auditLink); ok {
+					f.SetCellValue(sheet.name, cell, x.Name)
+					f.SetCellHyperLink(sheet.name, cell, fmt.Sprintf(iServerObjectURL, x.ObjectId), "External")
+					f.SetCellStyle(sheet.name, cell, cell, link)
+					continue
+				}
+				f.SetCellValue(sheet.name, cell, value)
+				f.SetCellStyle(sheet.name, cell, cell, wrap)
+			}
+		}
+		lastRow := len(sheet.rows) + 1
+		if lastRow < 2 {
+			lastRow = 2
+		}
+		if sheet.lifecycle > 0 {
+			column, _ := excelize.ColumnNumberToName(sheet.lifecycle)
+			if err := f.SetConditionalFormat(sheet.name, fmt.Sprintf("%[1]s2:%[1]s%[2]d", column, lastRow), lifecycles); err != nil {
+				return err
+			}
+		}
+		if err := f.AddTable(sheet.name, &excelize.Table{
+			Range:     fmt.Sprintf("A1:%s%d", lastColumn, lastRow),
+			Name:      strings.ReplaceAll(sheet.name, " ", ""),
+			StyleName: "TableStyleLight9",
+		}); err != nil {
+			return err
+		}
+		if err := f.SetPanes(sheet.name, &excelize.Panes{
+			Freeze:      true,
+			YSplit:      1,
+			TopLeftCell: "A2",
+			ActivePane:  "bottomLeft",
+		}); err != nil {
+			return err
+		}
+	}
+	return f.SaveAs(fileName)
+}
+
+// The domain's objects that aren't retired, with everything the workbook and quality rules need
+func fetchAuditObjects(domain string) ([]auditObject, []azure.RelationStruct, error) {
+	attributes := append([]string{}, qualityAttributes...)
+	for _, x := range auditColumns {
+		if !containsString(attributes, x.Attribute) {
+			attributes = append(attributes, x.Attribute)
+		}
+	}
+	objects, err := az.GetDomainObjects(domain, qualityObjectTypes, attributes, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	audit, relations := buildAuditObjects(objects, az.FindRelations)
+	return audit, relations, nil
+}
+
+// Fetch the domain's objects and their relationships, and save the workbook with today's date
+func CreateDomainWorkbook(domain, savePath string) (string, error) {
+	audit, relations, issues, err := checkDomainQuality(domain)
+	if err != nil {
+		return "", err
+	}
+	fileName := filepath.Join(savePath, domainWorkbookName(domain, time.Now()))
+	return fileName, createDomainWorkbook(audit, relations, issues, fileName)
+}
+
+func showDomainWorkbookDialog(thenWindow fyne.Window) {
+	domain := myApp.Preferences().StringWithFallback("Department", "")
+	if domain == "" {
+		dialog.ShowInformation("Excel Audit", "Choose a domain in Settings first", thenWindow)
+		return
+	}
+	UpdateMessage("Building the " + domain + " workbook")
+	go func() {
+		fileName, err := CreateDomainWorkbook(domain, getSavePath())
+		UpdateMessage("Ready")
+		if err != nil {
+			dialog.ShowError(err, thenWindow)
+			return
+		}
+		dialog.ShowInformation("Excel Audit", "Saved "+fileName, thenWindow)
+	}()
+}
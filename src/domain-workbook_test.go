@@ -0,0 +1,81 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+	azure "vonexplaino.com/m/v2/vondiagram/azure"
+)
+
+func TestCreateDomainWorkbook(t *testing.T) {
+	pac := "Physical Application Component"
+	ptc := "Physical Technology Component"
+	testObject := func(id, objectType string, attributes ...string) azure.IServerObjectStruct {
+		x := azure.IServerObjectStruct{Name: id, ObjectId: id}
+		x.ObjectType.Name = objectType
+		for i := 0; i+1 < len(attributes); i += 2 {
+			x.AttributeValues = append(x.AttributeValues, azure.AttributeValue{AttributeName: attributes[i], StringValue: attributes[i+1]})
+		}
+		return x
+	}
+	objects := []azure.IServerObjectStruct{
+		testObject("App", pac, "Owner", "Pat", "Department", "ITS", "Serviceability characteristics", "Gold", "Lifecycle Status", "Live"),
+		testObject("Server", ptc, "Lifecycle Status", "Phasing Out"),
+	}
+	rels := []azure.RelationStruct{
+		testRelation("r1", "App", pac, "is hosted on", "Server", ptc),
+		testRelation("r2", "App", pac, "supports", "Enrolment", "Capability"),
+	}
+	audit, relations := buildAuditObjects(objects, func(id string) []azure.RelationStruct {
+		toReturn := []azure.RelationStruct{}
+		for _, x := range rels {
+			if x.LeadObjectId == id || x.MemberObjectId == id {
+				toReturn = append(toReturn, x)
+			}
+		}
+		return toReturn
+	})
+	assert.Len(t, relations, 2)
+	issues := runQualityRules(qualityRules, audit, newQualityContext(objects, nil, time.Now()))
+	found := []string{}
+	for _, x := range issues {
+		found = append(found, x.Object.Name+" "+x.Rule.Name+": "+x.Message)
+	}
+	assert.Equal(t, []string{
+		"Server missing-owner: No Owner",
+		"App missing-description: No Description",
+		"App live-without-live-date: Live but has no Live date",
+		"Server missing-description: No Description",
+		"Server missing-department: No Department",
+		"Server missing-serviceability: No Serviceability characteristics",
+	}, found)
+
+	assert.Equal(t, "iServer Audit Teaching and Learning 2026-03-04.xlsx", domainWorkbookName("Teaching and Learning/", time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)))
+	fileName := filepath.Join(t.TempDir(), "audit.xlsx")
+	assert.NoError(t, createDomainWorkbook(audit, relations, issues, fileName))
+	f, err := excelize.OpenFile(fileName)
+	assert.NoError(t, err)
+	defer f.Close()
+	assert.Equal(t, []string{"Applications", "Technology", "Logical", "Relationships", "Capabilities", "Data Quality"}, f.GetSheetList())
+
+	rows, _ := f.GetRows("Applications")
+	assert.Equal(t, []string{"App", "App", "Pat", "ITS", "Gold", "Live", "Enrolment", "", "", "Server"}, rows[1])
+	visible, _ := f.GetColVisible("Applications", "A")
+	assert.False(t, visible)
+	link, target, _ := f.GetCellHyperLink("Applications", "B2")
+	assert.True(t, link)
+	assert.Equal(t, "https://griffith.iserver365.com/object/App/details", target)
+	formats, _ := f.GetConditionalFormats("Technology")
+	assert.Len(t, formats["F2:F2"], len(lifecycleFills))
+
+	rows, _ = f.GetRows("Relationships")
+	assert.Equal(t, []string{"r1", "App", pac, "is hosted on", "Server", ptc}, rows[1])
+	rows, _ = f.GetRows("Capabilities")
+	assert.Equal(t, []string{"Enrolment", "Enrolment", "1", "App"}, rows[1][:4])
+	rows, _ = f.GetRows("Data Quality")
+	assert.Len(t, rows, 7)
+	assert.Equal(t, []string{"Server", "Server", ptc, "Error", "missing-owner", "No Owner"}, rows[1])
+}
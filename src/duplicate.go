@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	azure "vonexplaino.com/m/v2/vondiagram/azure"
+)
+
+/**
+** Duplicate - start a new object from an existing one. Its attributes are
+** copied, apart from its name and the form's identity fields, and any of its
+** relationships can be recreated against the same objects. Nothing is
+** created until the copy has been reviewed.
+**/
+
+type duplicatedRelation struct {
+	description string
+	err         error
+}
+
+// The values a copy starts with: everything set, except the name and identity fields
+func duplicateValues(form formDefinition, values map[string]editValue) map[string]editValue {
+	toReturn := map[string]editValue{}
+	for name, x := range values {
+		if name == "Title" || x.normalised() == "" {
+			continue
+		}
+		if field, ok := form.field(name); ok && field.Identity {
+			continue
+		}
+		toReturn[name] = x
+	}
+	return toReturn
+}
+
+// The relationship with the copy at whichever end the original was
+func duplicateRelation(x azure.RelationStruct, fromId, toId string) azure.RelationStruct {
+	if x.LeadObjectId == fromId {
+		x.LeadObjectId = toId
+	}
+	if x.MemberObjectId == fromId {
+		x.MemberObjectId = toId
+	}
+	return x
+}
+
+// Create the copy, then each chosen relationship for it; an error means nothing was created
+func createDuplicate(
+	name string,
+	changes []editChange,
+	relations []azure.RelationStruct,
+	fromId string,
+	save func(name string, stringValues, selectValues, dateValues map[string]string) (bool, string, string),
+	relate func(relationshipTypeId, relationshipTypePairId, leadObjectId, memberObjectId string) error,
+) (string, []duplicatedRelation, error) {
+	stringValues, selectValues, dateValues := editChangeValues(changes)
+	stringValues["Title"] = name
+	success, message, id := save(name, stringValues, selectValues, dateValues)
+	if !success || id == "" {
+		return "", nil, fmt.Errorf("could not create %s: %s", name, message)
+	}
+	results := []duplicatedRelation{}
+	for _, x := range relations {
+		result := duplicatedRelation{description: relationDescription(x)}
+		copied := duplicateRelation(x, fromId, id)
+		if copied.RelationshipType.RelationshipTypeId == "" || copied.RelationshipTypePairId == "" {
+			result.err = fmt.Errorf("its relationship type wasn't loaded; refresh and try again")
+		} else {
+			result.err = relate(copied.RelationshipType.RelationshipTypeId, copied.RelationshipTypePairId, copied.LeadObjectId, copied.MemberObjectId)
+		}
+		results = append(results, result)
+	}
+	return id, results, nil
+}
+
+func duplicateResultSummary(results []duplicatedRelation) string {
+	lines := []string{}
+	for _, x := range results {
+		if x.err != nil {
+			lines = append(lines, fmt.Sprintf("Failed: %s (%s)", x.description, x.err))
+		} else {
+			lines = append(lines, "Created: "+x.description)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Review what will be copied, then create the copy and open it for editing
+func showDuplicateWindow(basics azure.IServerObjectStruct, allFields modelFields, relations []azure.RelationStruct) {
+	duplicateWindow := addWindowFor("Duplicate "+basics.Name, 700, 600)
+
+	labels, _ := allFields.fieldLabels()
+	changes := diffEditValues(map[string]editValue{}, duplicateValues(allFields.form, allFields.editValues()), labels)
+	copied := []string{}
+	for _, x := range changes {
+		copied = append(copied, fmt.Sprintf("%s: %s", x.label, x.new))
+	}
+	copiedLabel := widget.NewLabel(strings.Join(copied, "\n"))
+	copiedLabel.Wrapping = fyne.TextWrapWord
+
+	name := widget.NewEntry()
+	name.SetText("Copy of " + basics.Name)
+
+	// Relationships are offered by description, numbered when two read the same
+	byDescription := map[string]azure.RelationStruct{}
+	descriptions := []string{}
+	for _, x := range relations {
+		description := relationDescription(x)
+		for i := 2; ; i++ {
+			if _, ok := byDescription[description]; !ok {
+				break
+			}
+			description = fmt.Sprintf("%s (%d)", relationDescription(x), i)
+		}
+		byDescription[description] = x
+		descriptions = append(descriptions, description)
+	}
+	chosen := widget.NewCheckGroup(descriptions, func(bob []string) {})
+
+	create := widget.NewButton("Create copy", func() {
+		if strings.TrimSpace(name.Text) == "" {
+			dialog.ShowError(fmt.Errorf("the copy needs a name"), duplicateWindow)
+			return
+		}
+		toRelate := []azure.RelationStruct{}
+		for _, x := range chosen.Selected {
+			toRelate = append(toRelate, byDescription[x])
+		}
+		dialog.ShowConfirm(
+			"Duplicate",
+			fmt.Sprintf("Create %s with %d attributes and %d relationships?", name.Text, len(changes), len(toRelate)),
+			func(ok bool) {
+				if !ok {
+					return
+				}
+				id, results, err := createDuplicate(
+					name.Text,
+					changes,
+					toRelate,
+					basics.ObjectId,
+					func(newName string, stringValues, selectValues, dateValues map[string]string) (bool, string, string) {
+						return az.SaveObjectFields("", newName, basics.ObjectType.Name, stringValues, selectValues, dateValues)
+					},
+					az.CreateRelationship,
+				)
+				if err != nil {
+					dialog.ShowError(err, duplicateWindow)
+					return
+				}
+				message := fmt.Sprintf("Created %s", name.Text)
+				if len(results) > 0 {
+					message += "\n\n" + duplicateResultSummary(results)
+				}
+				dialog.ShowInformation("Duplicated", message, duplicateWindow)
+				createEditWindow(
+					fmt.Sprintf("Details for %s", name.Text),
+					az.GetImportantFields(id, allFields.form.Code),
+					az.FindRelations(id),
+				)
+			},
+			duplicateWindow,
+		)
+	})
+
+	duplicateWindow.SetContent(container.NewBorder(
+		widget.NewForm(widget.NewFormItem("Name", name)),
+		create,
+		nil,
+		nil,
+		container.NewVScroll(container.NewVBox(
+			widget.NewLabelWithStyle("Attributes copied", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			copiedLabel,
+			widget.NewLabelWithStyle("Relationships to copy", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			chosen,
+		)),
+	))
+	duplicateWindow.Show()
+}
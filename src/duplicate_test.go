@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	azure "vonexplaino.com/m/v2/vondiagram/azure"
+)
+
+func TestDuplicateValues(t *testing.T) {
+	values := map[string]editValue{
+		"Title":       {kind: "string", text: "Canvas"},
+		"Alias":       {kind: "string", text: "LMS"},
+		"Description": {kind: "string", text: "Learning management"},
+		"Vendor":      {kind: "string", text: ""},
+		"Categories":  {kind: "check", chosen: []string{"Teaching"}},
+		roadmapLive:   {kind: "date", text: "2020/01/01"},
+	}
+	copied := duplicateValues(formFor("Physical Application Component"), values)
+	assert.Equal(t, map[string]editValue{
+		"Description": values["Description"],
+		"Categories":  values["Categories"],
+		roadmapLive:   values[roadmapLive],
+	}, copied)
+}
+
+func relationFor(id, lead, member string) azure.RelationStruct {
+	x := azure.RelationStruct{RelationshipId: id, RelationshipTypePairId: "pair", LeadObjectId: lead, MemberObjectId: member}
+	x.RelationshipType.RelationshipTypeId = "type"
+	x.RelationshipType.LeadToMemberDirection = "uses"
+	x.LeadObject.Name = lead
+	x.MemberObject.Name = member
+	return x
+}
+
+func TestCreateDuplicate(t *testing.T) {
+	changes := diffEditValues(map[string]editValue{}, map[string]editValue{
+		"Description": {kind: "string", text: "Learning management"},
+		"Owner":       {kind: "select", text: "Pat"},
+	}, nil)
+	noType := relationFor("r3", "canvas", "db")
+	noType.RelationshipTypePairId = ""
+	relations := []azure.RelationStruct{relationFor("r1", "canvas", "db"), relationFor("r2", "portal", "canvas"), noType}
+
+	saved := map[string]string{}
+	related := [][]string{}
+	id, results, err := createDuplicate(
+		"Canvas 2",
+		changes,
+		relations,
+		"canvas",
+		func(name string, stringValues, selectValues, dateValues map[string]string) (bool, string, string) {
+			saved = stringValues
+			assert.Equal(t, map[string]string{"Owner": "Pat"}, selectValues)
+			return true, "", "copy"
+		},
+		func(relationshipTypeId, relationshipTypePairId, leadObjectId, memberObjectId string) error {
+			related = append(related, []string{relationshipTypeId, relationshipTypePairId, leadObjectId, memberObjectId})
+			if leadObjectId == "portal" {
+				return errors.New("denied")
+			}
+			return nil
+		},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "copy", id)
+	assert.Equal(t, map[string]string{"Title": "Canvas 2", "Description": "Learning management"}, saved)
+	assert.Equal(t, [][]string{{"type", "pair", "copy", "db"}, {"type", "pair", "portal", "copy"}}, related)
+	assert.Len(t, results, 3)
+	assert.NoError(t, results[0].err)
+	assert.EqualError(t, results[1].err, "denied")
+	assert.Error(t, results[2].err)
+	assert.Equal(t, "Created: canvas uses db\nFailed: portal uses canvas (denied)\nFailed: canvas uses db (its relationship type wasn't loaded; refresh and try again)", duplicateResultSummary(results))
+
+	// Nothing is related when the copy couldn't be made
+	_, results, err = createDuplicate("Canvas 2", changes, relations, "canvas",
+		func(name string, stringValues, selectValues, dateValues map[string]string) (bool, string, string) {
+			return false, "no permission", ""
+		},
+		func(relationshipTypeId, relationshipTypePairId, leadObjectId, memberObjectId string) error {
+			t.Fail()
+			return nil
+		},
+	)
+	assert.EqualError(t, err, "could not create Canvas 2: no permission")
+	assert.Empty(t, results)
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+	azure "vonexplaino.com/m/v2/vondiagram/azure"
+)
+
+/**
+** Edit forms - the edit window's layout for each object type: its tabs,
+** rows and fields, which widget each field uses and how it's validated.
+** The bundled definitions can be replaced, or new object types added, by an
+** edit-forms.json in the user config directory's vondiagram folder. The
+** definition with an empty ObjectType is used for any type without one.
+**/
+
+//go:embed edit-forms.json
+var editFormsFile []byte
+
+var (
+	// Choices loaded from iServer's attribute configuration
+	choicesIServer = "iServer"
+	// The product managers from Settings
+	choicesProductManagers = "ProductManagers"
+	// Only the field's Options
+	choicesFixed = "Fixed"
+)
+
+var formWidgets = map[string]bool{"string": true, "multiline": true, "select": true, "radio": true, "check": true, "date": true}
+
+type formField struct {
+	Label     string `json:"Label"`
+	Attribute string `json:"Attribute"`
+	// string, multiline, select, radio, check or date
+	Widget string `json:"Widget"`
+	// Other spellings iServer uses for the attribute
+	Aliases   []string `json:"Aliases,omitempty"`
+	Required  bool     `json:"Required,omitempty"`
+	MaxLength int      `json:"MaxLength,omitempty"`
+	// Rows shown for a multiline field
+	Lines int `json:"Lines,omitempty"`
+	// Where a select, radio or check gets its choices; iServer when empty
+	Choices string   `json:"Choices,omitempty"`
+	Options []string `json:"Options,omitempty"`
+	// Names this object alone, so isn't copied when it's duplicated
+	Identity bool `json:"Identity,omitempty"`
+}
+
+type formSection struct {
+	Title string        `json:"Title"`
+	Rows  [][]formField `json:"Rows"`
+}
+
+type formDefinition struct {
+	ObjectType string `json:"ObjectType"`
+	// The short name used for the type, such as PAC
+	Code     string        `json:"Code"`
+	Sections []formSection `json:"Sections"`
+}
+
+var loadedEditForms map[string]formDefinition
+
+func userEditFormsFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "vondiagram", "edit-forms.json")
+}
+
+func parseEditForms(contents []byte) ([]formDefinition, error) {
+	forms := []formDefinition{}
+	if err := json.Unmarshal(contents, &forms); err != nil {
+		return forms, err
+	}
+	for _, x := range forms {
+		for _, y := range x.fields() {
+			if y.Attribute == "" {
+				return forms, fmt.Errorf("%s form: %q has no Attribute", x.Code, y.Label)
+			}
+			if !formWidgets[y.Widget] {
+				return forms, fmt.Errorf("%s form: %q has an unknown Widget %q", x.Code, y.Label, y.Widget)
+			}
+			if !containsString([]string{"", choicesIServer, choicesProductManagers, choicesFixed}, y.Choices) {
+				return forms, fmt.Errorf("%s form: %q has unknown Choices %q", x.Code, y.Label, y.Choices)
+			}
+		}
+	}
+	return forms, nil
+}
+
+// The bundled forms, with any in the user's file replacing those for the same object type
+func loadEditForms(bundled []byte, userFile string) (map[string]formDefinition, error) {
+	toReturn := map[string]formDefinition{}
+	forms, err := parseEditForms(bundled)
+	if err != nil {
+		return toReturn, err
+	}
+	for _, x := range forms {
+		toReturn[x.ObjectType] = x
+	}
+	if userFile == "" {
+		return toReturn, nil
+	}
+	contents, err := os.ReadFile(userFile)
+	if os.IsNotExist(err) {
+		return toReturn, nil
+	}
+	if err != nil {
+		return toReturn, err
+	}
+	forms, err = parseEditForms(contents)
+	if err != nil {
+		return toReturn, fmt.Errorf("%s: %w", userFile, err)
+	}
+	for _, x := range forms {
+		toReturn[x.ObjectType] = x
+	}
+	return toReturn, nil
+}
+
+func editForms() map[string]formDefinition {
+	if loadedEditForms == nil {
+		var err error
+		loadedEditForms, err = loadEditForms(editFormsFile, userEditFormsFile())
+		if err != nil {
+			fyne.LogError("Could not load the edit forms", err)
+		}
+	}
+	return loadedEditForms
+}
+
+// The form for an object type, or the generic one if it doesn't have its own
+func formFor(objectType string) formDefinition {
+	if x, ok := editForms()[objectType]; ok {
+		return x
+	}
+	return editForms()[""]
+}
+
+// Fetch every attribute the forms show, and search every type that has its own form
+func applyEditForms() {
+	types := []string{}
+	for _, x := range editForms() {
+		azure.ImportantFields[x.Code] = x.attributes()
+		if x.ObjectType != "" {
+			types = append(types, x.ObjectType)
+		}
+	}
+	sort.Strings(types)
+	azure.SearchObjectTypes = types
+}
+
+// Object types with their own form, rather than the generic one
+func hasEditForm(objectType string) bool {
+	_, ok := editForms()[objectType]
+	return ok && objectType != ""
+}
+
+func (f formDefinition) fields() []formField {
+	toReturn := []formField{}
+	for _, x := range f.Sections {
+		for _, y := range x.Rows {
+			toReturn = append(toReturn, y...)
+		}
+	}
+	return toReturn
+}
+
+// The attributes to fetch from iServer, in every spelling
+func (f formDefinition) attributes() []string {
+	toReturn := []string{}
+	for _, x := range f.fields() {
+		if x.Attribute != "Title" {
+			toReturn = append(toReturn, x.Attribute)
+		}
+		toReturn = append(toReturn, x.Aliases...)
+	}
+	return toReturn
+}
+
+func (f formDefinition) field(attribute string) (formField, bool) {
+	for _, x := range f.fields() {
+		if x.Attribute == attribute {
+			return x, true
+		}
+	}
+	return formField{}, false
+}
+
+// The form's name for an attribute iServer has returned
+func (f formDefinition) attributeName(name string) string {
+	for _, x := range f.fields() {
+		if x.Attribute == name {
+			return name
+		}
+	}
+	for _, x := range f.fields() {
+		for _, y := range x.Aliases {
+			if strings.EqualFold(y, name) {
+				return x.Attribute
+			}
+		}
+	}
+	return name
+}
+
+func (f formDefinition) fieldRules() map[string]fieldRule {
+	toReturn := map[string]fieldRule{}
+	for _, x := range f.fields() {
+		if x.Required || x.MaxLength > 0 {
+			toReturn[x.Attribute] = fieldRule{Required: x.Required, MaxLength: x.MaxLength}
+		}
+	}
+	return toReturn
+}
+
+// Widgets for each of the form's fields, laid out in its sections
+func (f formDefinition) modelFields() modelFields {
+	toReturn := modelFields{
+		stringValues: map[string]*widget.Entry{},
+		selectValues: map[string]*widget.Select{},
+		radioValues:  map[string]*widget.RadioGroup{},
+		checkValues:  map[string]*widget.CheckGroup{},
+		dateValues:   map[string]*widget.Entry{},
+		sections:     map[int]sectionStruct{},
+		form:         f,
+	}
+	for i, x := range f.Sections {
+		section := sectionStruct{title: x.Title, fields: map[int]map[int]fieldsStruct{}}
+		for j, y := range x.Rows {
+			section.fields[j] = map[int]fieldsStruct{}
+			for k, z := range y {
+				kind := z.Widget
+				switch z.Widget {
+				case "string":
+					toReturn.stringValues[z.Attribute] = widget.NewEntry()
+				case "multiline":
+					kind = "string"
+					toReturn.stringValues[z.Attribute] = widget.NewMultiLineEntry()
+					toReturn.stringValues[z.Attribute].Wrapping = fyne.TextWrapWord
+					if z.Lines > 0 {
+						toReturn.stringValues[z.Attribute].SetMinRowsVisible(z.Lines)
+					}
+				case "select":
+					toReturn.selectValues[z.Attribute] = widget.NewSelect(append([]string{}, z.Options...), func(bob string) {})
+				case "radio":
+					toReturn.radioValues[z.Attribute] = widget.NewRadioGroup(append([]string{}, z.Options...), func(bob string) {})
+				case "check":
+					toReturn.checkValues[z.Attribute] = widget.NewCheckGroup(append([]string{}, z.Options...), func(bob []string) {})
+					toReturn.checkValues[z.Attribute].Horizontal = true
+				case "date":
+					toReturn.dateValues[z.Attribute] = widget.NewEntry()
+				}
+				section.fields[j][k] = fieldsStruct{z.Label, kind, z.Attribute}
+			}
+		}
+		toReturn.sections[i] = section
+	}
+	return toReturn
+}
+
+// A blank object of the type, with every choice field ready to load its choices
+func newObjectTemplate(objectType string) azure.IServerObjectStruct {
+	newObject := azure.IServerObjectStruct{}
+	newObject.ObjectType.Name = objectType
+	for _, x := range formFor(objectType).fields() {
+		switch x.Widget {
+		case "select", "radio", "check":
+			newObject.AttributeValues = append(newObject.AttributeValues, azure.AttributeValue{AttributeName: x.Attribute})
+		}
+	}
+	return newObject
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"fyne.io/fyne/v2/test"
+	"github.com/stretchr/testify/assert"
+	azure "vonexplaino.com/m/v2/vondiagram/azure"
+)
+
+func TestLoadEditForms(t *testing.T) {
+	test.NewTempApp(t)
+	forms, err := loadEditForms(editFormsFile, "")
+	assert.NoError(t, err)
+	pac := forms["Physical Application Component"]
+	assert.Equal(t, "PAC", pac.Code)
+	assert.Contains(t, pac.attributes(), "Internal: Live Date")
+	assert.Contains(t, pac.attributes(), roadmapLive)
+	assert.NotContains(t, pac.attributes(), "Title")
+	assert.Equal(t, roadmapLive, pac.attributeName("internal: live date"))
+	assert.Equal(t, "Alias", pac.attributeName("Alias"))
+	assert.Equal(t, fieldRule{Required: true, MaxLength: 4000}, pac.fieldRules()["Description"])
+
+	fields := pac.modelFields()
+	assert.Len(t, fields.sections, 3)
+	assert.Equal(t, fieldsStruct{"Links", "string", "Links"}, fields.sections[0].fields[4][0])
+	assert.True(t, fields.stringValues["Links"].MultiLine)
+	assert.Equal(t, []string{"True", "False"}, fields.selectValues["GU::Managed outside of DS"].Options)
+	assert.Contains(t, fields.dateValues, roadmapRetirement)
+
+	// Every row of the PTC form is shown, in order
+	ptc := forms["Physical Technology Component"].modelFields()
+	assert.Equal(t, "Department", ptc.sections[1].fields[3][0].valuesIndex)
+
+	// The user's forms replace the bundled ones for the same type, and add new types
+	userFile := filepath.Join(t.TempDir(), "edit-forms.json")
+	os.WriteFile(userFile, []byte(`[
+		{"ObjectType": "Physical Technology Component", "Code": "PTC", "Sections": [{"Title": "Only", "Rows": [[{"Label": "Name", "Attribute": "Title", "Widget": "string"}]]}]},
+		{"ObjectType": "Risk", "Code": "RSK", "Sections": [{"Title": "Basic", "Rows": [[{"Label": "Level", "Attribute": "Level", "Widget": "radio", "Choices": "Fixed", "Options": ["1", "2"]}]]}]}
+	]`), 0644)
+	forms, err = loadEditForms(editFormsFile, userFile)
+	assert.NoError(t, err)
+	assert.Len(t, forms["Physical Technology Component"].fields(), 1)
+	assert.Equal(t, []string{"Level"}, forms["Risk"].attributes())
+	assert.Equal(t, "PAC", forms["Physical Application Component"].Code)
+
+	os.WriteFile(userFile, []byte(`[{"ObjectType": "Risk", "Code": "RSK", "Sections": [{"Title": "Basic", "Rows": [[{"Label": "Level", "Attribute": "Level", "Widget": "slider"}]]}]}]`), 0644)
+	forms, err = loadEditForms(editFormsFile, userFile)
+	assert.ErrorContains(t, err, `unknown Widget "slider"`)
+	assert.NotContains(t, forms, "Risk")
+	assert.Contains(t, forms, "")
+}
+
+func TestApplyEditForms(t *testing.T) {
+	applyEditForms()
+	for objectType, code := range map[string]string{
+		"Physical Application Component": "PAC",
+		"Physical Technology Component":  "PTC",
+		"Logical Application Component":  "LAC",
+		"Physical Data Component":        "PDC",
+		"Interface":                      "INT",
+		"Capability":                     "CAP",
+		"Physical Technology Group":      "PTG",
+	} {
+		assert.True(t, hasEditForm(objectType), objectType)
+		assert.Equal(t, code, formFor(objectType).Code)
+		assert.Contains(t, azure.SearchObjectTypes, objectType)
+		assert.NotEmpty(t, azure.ImportantFields[code], objectType)
+		assert.NotEmpty(t, azure.ObjectTypeIdFor(objectType), objectType)
+		assert.True(t, fieldRulesFor(objectType)["Title"].Required, objectType)
+	}
+	assert.False(t, hasEditForm("Risk"))
+	assert.Equal(t, "GEN", formFor("Risk").Code)
+	assert.NotContains(t, azure.SearchObjectTypes, "")
+}
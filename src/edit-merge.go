@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	azure "vonexplaino.com/m/v2/vondiagram/azure"
+)
+
+/**
+** Edit merge - when someone else saves an object while it's open in the
+** edit window, their values are compared with what was loaded and what's
+** been edited. Fields only one side changed are merged; where both changed
+** the user picks whose value to keep, or abandons the save.
+**/
+
+var (
+	mergeTheirs = "Theirs"
+	mergeMine   = "Mine"
+)
+
+type mergeRow struct {
+	name  string
+	label string
+	// As loaded, as iServer has it now, and as edited
+	original, theirs, mine editValue
+}
+
+func (x mergeRow) theirsChanged() bool {
+	return x.theirs.normalised() != x.original.normalised()
+}
+
+func (x mergeRow) mineChanged() bool {
+	return x.mine.normalised() != x.original.normalised()
+}
+
+// Both sides changed the field, to different values
+func (x mergeRow) conflict() bool {
+	return x.theirsChanged() && x.mineChanged() && x.theirs.normalised() != x.mine.normalised()
+}
+
+// An object's values as the edit window would show them
+func (m modelFields) editValuesFrom(x azure.IServerObjectStruct) map[string]editValue {
+	toReturn := map[string]editValue{}
+	for name, y := range m.editValues() {
+		toReturn[name] = editValue{kind: y.kind}
+	}
+	toReturn["Title"] = editValue{kind: "string", text: x.Name}
+	for _, y := range x.AttributeValues {
+		y.AttributeName = m.form.attributeName(y.AttributeName)
+		value, ok := toReturn[y.AttributeName]
+		if !ok {
+			continue
+		}
+		switch value.kind {
+		case "check":
+			for _, elem := range strings.Split(y.StringValue, ",") {
+				value.chosen = append(value.chosen, strings.Trim(elem, " "))
+			}
+			value.text = strings.Join(value.chosen, ",")
+		case "date":
+			value.text = strings.Replace(y.StringValue, "T00:00:00Z", "", 1)
+		case "select":
+			value.text = y.StringValue
+			if y.AttributeName == "Build" {
+				value.text = strings.Split(y.StringValue, " ")[0]
+			}
+		default:
+			value.text = y.StringValue
+		}
+		toReturn[y.AttributeName] = value
+	}
+	return toReturn
+}
+
+// Put values back into the edit window's widgets
+func (m modelFields) setEditValues(values map[string]editValue) {
+	for name, x := range values {
+		if y, ok := m.stringValues[name]; ok {
+			y.SetText(x.text)
+		}
+		if y, ok := m.selectValues[name]; ok {
+			y.Selected = x.text
+			y.Refresh()
+		}
+		if y, ok := m.radioValues[name]; ok {
+			y.Selected = x.text
+			y.Refresh()
+		}
+		if y, ok := m.checkValues[name]; ok {
+			y.Selected = append([]string{}, x.chosen...)
+			y.Refresh()
+		}
+		if y, ok := m.dateValues[name]; ok {
+			y.SetText(x.text)
+		}
+	}
+}
+
+// The fields either side has changed since the object was loaded
+func threeWayRows(original, theirs, mine map[string]editValue, labels map[string]string) []mergeRow {
+	toReturn := []mergeRow{}
+	for name, x := range mine {
+		row := mergeRow{name: name, label: labels[name], original: original[name], theirs: theirs[name], mine: x}
+		if row.label == "" {
+			row.label = name
+		}
+		if row.theirs.kind == "" {
+			row.theirs.kind = x.kind
+		}
+		if row.theirsChanged() || row.mineChanged() {
+			toReturn = append(toReturn, row)
+		}
+	}
+	sort.Slice(toReturn, func(i, j int) bool { return toReturn[i].label < toReturn[j].label })
+	return toReturn
+}
+
+// Take whichever side changed each field, and the chosen side where both did
+func mergeEditValues(mine map[string]editValue, rows []mergeRow, keepMine map[string]bool) map[string]editValue {
+	toReturn := map[string]editValue{}
+	for name, x := range mine {
+		toReturn[name] = x
+	}
+	for _, x := range rows {
+		switch {
+		case x.conflict() && keepMine[x.name]:
+			toReturn[x.name] = x.mine
+		case x.conflict(), !x.mineChanged():
+			toReturn[x.name] = x.theirs
+		}
+	}
+	return toReturn
+}
+
+// Show the three versions side by side; merge is called with the fields to keep as edited
+func showEditMergeWindow(objectName, who string, rows []mergeRow, merge func(keepMine map[string]bool)) {
+	mergeWindow := addWindowFor("Changed in iServer: "+objectName, 800, 500)
+
+	keepMine := map[string]bool{}
+	grid := container.NewGridWithColumns(5,
+		widget.NewLabelWithStyle("Field", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewLabelWithStyle("When loaded", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewLabelWithStyle("Theirs", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewLabelWithStyle("Mine", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewLabelWithStyle("Keep", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+	)
+	conflicts := 0
+	for _, x := range rows {
+		var keep fyne.CanvasObject
+		switch {
+		case x.conflict():
+			conflicts++
+			name := x.name
+			choice := widget.NewRadioGroup([]string{mergeTheirs, mergeMine}, func(chosen string) {
+				keepMine[name] = chosen == mergeMine
+			})
+			choice.Horizontal = true
+			choice.Required = true
+			choice.SetSelected(mergeMine)
+			keep = choice
+		case x.mineChanged():
+			keep = widget.NewLabel(mergeMine)
+		default:
+			keep = widget.NewLabel(mergeTheirs)
+		}
+		for _, y := range []string{x.label, x.original.normalised(), x.theirs.normalised(), x.mine.normalised()} {
+			label := widget.NewLabel(y)
+			label.Wrapping = fyne.TextWrapWord
+			grid.Add(label)
+		}
+		grid.Add(keep)
+	}
+
+	mergeWindow.SetContent(container.NewBorder(
+		widget.NewLabel(fmt.Sprintf(
+			"%s was changed in iServer by %s after you opened it. %d fields were changed by both of you; choose which to keep.",
+			objectName,
+			who,
+			conflicts,
+		)),
+		container.NewGridWithColumns(2,
+			widget.NewButton("Abort, save nothing", func() {
+				mergeWindow.Close()
+			}),
+			widget.NewButton("Save merged", func() {
+				mergeWindow.Close()
+				merge(keepMine)
+			}),
+		),
+		nil,
+		nil,
+		container.NewVScroll(grid),
+	))
+	mergeWindow.Show()
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+
+	"fyne.io/fyne/v2/test"
+	"github.com/stretchr/testify/assert"
+	azure "vonexplaino.com/m/v2/vondiagram/azure"
+)
+
+func TestThreeWayMerge(t *testing.T) {
+	text := func(value string) editValue { return editValue{kind: "string", text: value} }
+	original := map[string]editValue{
+		"Title":       text("Canvas"),
+		"Description": text("Learning"),
+		"Alias":       text("LMS"),
+		"Vendor":      text("Instructure"),
+		"Supplier":    text("Instructure"),
+	}
+	theirs := map[string]editValue{
+		"Title":       text("Canvas"),
+		"Description": text("Learning management"),
+		"Alias":       text("Canvas LMS"),
+		"Vendor":      text("Instructure"),
+		"Supplier":    text("Reseller"),
+	}
+	mine := map[string]editValue{
+		"Title":       text("Canvas"),
+		"Description": text("Learning management system"),
+		"Alias":       text("Canvas LMS"),
+		"Vendor":      text("Instructure Inc"),
+		"Supplier":    text("Instructure"),
+	}
+	rows := threeWayRows(original, theirs, mine, map[string]string{"Supplier": "Reseller name"})
+	names := []string{}
+	conflicts := []string{}
+	for _, x := range rows {
+		names = append(names, x.label)
+		if x.conflict() {
+			conflicts = append(conflicts, x.name)
+		}
+	}
+	assert.Equal(t, []string{"Alias", "Description", "Reseller name", "Vendor"}, names)
+	assert.Equal(t, []string{"Description"}, conflicts)
+
+	merged := mergeEditValues(mine, rows, map[string]bool{})
+	assert.Equal(t, "Learning management", merged["Description"].text)
+	assert.Equal(t, "Canvas LMS", merged["Alias"].text)
+	assert.Equal(t, "Instructure Inc", merged["Vendor"].text)
+	assert.Equal(t, "Reseller", merged["Supplier"].text)
+	assert.Equal(t, "Learning management system", mergeEditValues(mine, rows, map[string]bool{"Description": true})["Description"].text)
+
+	// Only what differs from iServer now is saved
+	changes := diffEditValues(theirs, merged, nil)
+	assert.Len(t, changes, 1)
+	assert.Equal(t, "Vendor", changes[0].name)
+}
+
+func TestEditValuesFrom(t *testing.T) {
+	test.NewTempApp(t)
+	fields := formFor("Physical Application Component").modelFields()
+	x := azure.IServerObjectStruct{Name: "Canvas", AttributeValues: []azure.AttributeValue{
+		{AttributeName: "Categories", StringValue: "Teaching, Research"},
+		{AttributeName: "Build", StringValue: "Buy (SaaS)"},
+		{AttributeName: "Internal: Live Date", StringValue: "2021-01-01T00:00:00Z"},
+	}}
+	values := fields.editValuesFrom(x)
+	assert.Equal(t, "Canvas", values["Title"].text)
+	assert.Equal(t, []string{"Teaching", "Research"}, values["Categories"].chosen)
+	assert.Equal(t, "Buy", values["Build"].text)
+	assert.Equal(t, "2021-01-01", values[roadmapLive].text)
+	assert.Equal(t, editValue{kind: "string"}, values["Alias"])
+	assert.NotContains(t, values, "Internal: Live Date")
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/**
+** Edit save - compares the edit window's fields with what was loaded, so
+** only the attributes that changed are sent to iServer. Anything the window
+** didn't load stays as it is in iServer, rather than being saved as empty.
+**/
+
+type editChange struct {
+	name     string
+	label    string
+	kind     string
+	old, new string
+}
+
+func (x editChange) String() string {
+	return fmt.Sprintf("%s: %q → %q", x.label, x.old, x.new)
+}
+
+// A field's value as it would be saved, so unimportant differences don't count
+func (x editValue) normalised() string {
+	switch x.kind {
+	case "check":
+		chosen := []string{}
+		for _, y := range x.chosen {
+			if y = strings.TrimSpace(y); y != "" {
+				chosen = append(chosen, y)
+			}
+		}
+		sort.Strings(chosen)
+		return strings.Join(chosen, ",")
+	case "date":
+		return strings.ReplaceAll(strings.TrimSpace(x.text), "/", "-")
+	}
+	return strings.TrimSpace(x.text)
+}
+
+// What's different from the original values, in the order the fields are labelled
+func diffEditValues(original, current map[string]editValue, labels map[string]string) []editChange {
+	toReturn := []editChange{}
+	for name, x := range current {
+		old := original[name].normalised()
+		if old == x.normalised() {
+			continue
+		}
+		label := labels[name]
+		if label == "" {
+			label = name
+		}
+		toReturn = append(toReturn, editChange{name: name, label: label, kind: x.kind, old: old, new: x.normalised()})
+	}
+	sort.Slice(toReturn, func(i, j int) bool { return toReturn[i].label < toReturn[j].label })
+	return toReturn
+}
+
+// The changes split the way SaveObjectFields wants them
+func editChangeValues(changes []editChange) (map[string]string, map[string]string, map[string]string) {
+	stringValues := map[string]string{}
+	selectValues := map[string]string{}
+	dateValues := map[string]string{}
+	for _, x := range changes {
+		switch x.kind {
+		case "select", "check":
+			selectValues[x.name] = x.new
+		case "date":
+			dateValues[x.name] = x.new
+		default:
+			stringValues[x.name] = x.new
+		}
+	}
+	return stringValues, selectValues, dateValues
+}
+
+func editChangeSummary(changes []editChange) string {
+	lines := []string{}
+	for _, x := range changes {
+		lines = append(lines, x.String())
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffEditValues(t *testing.T) {
+	original := map[string]editValue{
+		"Title":                  {kind: "string", text: "Canvas"},
+		"Description":            {kind: "string", text: "Learning"},
+		"Owner":                  {kind: "select", text: "Pat"},
+		"Categories":             {kind: "check", chosen: []string{"Teaching", "Research"}},
+		roadmapLive:              {kind: "date", text: "2020-01-01"},
+		"Internal: Live Date":    {kind: "date", text: ""},
+		"Approved Usage":         {kind: "string", text: ""},
+		"Operational Importance": {kind: "radio", text: "High"},
+	}
+	current := map[string]editValue{
+		"Title":                  {kind: "string", text: "Canvas "},
+		"Description":            {kind: "string", text: "Learning management"},
+		"Owner":                  {kind: "select", text: "Sam"},
+		"Categories":             {kind: "check", chosen: []string{"Research", "Teaching"}},
+		roadmapLive:              {kind: "date", text: "2020/01/01"},
+		"Internal: Live Date":    {kind: "date", text: ""},
+		"Approved Usage":         {kind: "string", text: "Everyone"},
+		"Operational Importance": {kind: "radio", text: "Low"},
+		roadmapRetirement:        {kind: "date", text: "2030/06/30"},
+	}
+	labels := map[string]string{"Owner": "Product Manager", roadmapRetirement: "Retirement"}
+	changes := diffEditValues(original, current, labels)
+	summary := []string{}
+	for _, x := range changes {
+		summary = append(summary, x.String())
+	}
+	assert.Equal(t, []string{
+		`Approved Usage: "" → "Everyone"`,
+		`Description: "Learning" → "Learning management"`,
+		`Operational Importance: "High" → "Low"`,
+		`Product Manager: "Pat" → "Sam"`,
+		`Retirement: "" → "2030-06-30"`,
+	}, summary)
+
+	stringValues, selectValues, dateValues := editChangeValues(changes)
+	assert.Equal(t, map[string]string{"Approved Usage": "Everyone", "Description": "Learning management", "Operational Importance": "Low"}, stringValues)
+	assert.Equal(t, map[string]string{"Owner": "Sam"}, selectValues)
+	assert.Equal(t, map[string]string{roadmapRetirement: "2030-06-30"}, dateValues)
+
+	assert.Empty(t, diffEditValues(current, current, labels))
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	azure "vonexplaino.com/m/v2/vondiagram/azure"
+)
+
+/**
+** Edit validation - checks the edit window's fields before they're saved.
+** Each object type's form sets its required fields and maximum lengths;
+** dates must be dates, in lifecycle order, chosen values must be valid
+** choices and links must be `Name (url)`. Problems are shown under each
+** field and on its tab, and the save doesn't go ahead until they're fixed.
+**/
+
+type fieldRule struct {
+	Required  bool
+	MaxLength int
+}
+
+// The Internal: dates, in the order an object goes through them
+var lifecycleDateOrder = []string{roadmapInDevelopment, roadmapLive, roadmapPhaseOut, roadmapRetirement}
+
+// What's in one of the edit window's fields
+type editValue struct {
+	kind   string
+	text   string
+	chosen []string
+}
+
+func fieldRulesFor(objectType string) map[string]fieldRule {
+	return formFor(objectType).fieldRules()
+}
+
+func (m modelFields) editValues() map[string]editValue {
+	toReturn := map[string]editValue{}
+	for i, x := range m.stringValues {
+		toReturn[i] = editValue{kind: "string", text: x.Text}
+	}
+	for i, x := range m.selectValues {
+		toReturn[i] = editValue{kind: "select", text: x.Selected}
+	}
+	for i, x := range m.radioValues {
+		toReturn[i] = editValue{kind: "radio", text: x.Selected}
+	}
+	for i, x := range m.checkValues {
+		toReturn[i] = editValue{kind: "check", text: strings.Join(x.Selected, ","), chosen: x.Selected}
+	}
+	for i, x := range m.dateValues {
+		toReturn[i] = editValue{kind: "date", text: x.Text}
+	}
+	return toReturn
+}
+
+// Field labels, and the tab each field is on, from the layout
+func (m modelFields) fieldLabels() (map[string]string, map[string]int) {
+	labels := map[string]string{}
+	tabs := map[string]int{}
+	for i, sec := range m.sections {
+		for _, row := range sec.fields {
+			for _, fld := range row {
+				labels[fld.valuesIndex] = fld.label
+				tabs[fld.valuesIndex] = i
+			}
+		}
+	}
+	return labels, tabs
+}
+
+func validLink(entry string) bool {
+	bits := azure.LinkPattern.FindStringSubmatch(entry)
+	if len(bits) < 3 || bits[1] == "" {
+		return false
+	}
+	u, err := url.ParseRequestURI(strings.TrimSpace(bits[2]))
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func validChoice(name, value string) bool {
+	choices := azure.ValidChoices[name]
+	if value == "" || len(choices) == 0 {
+		return true
+	}
+	_, ok := choices[value]
+	return ok
+}
+
+// The problem with each field, by attribute name; empty when it can be saved
+func validateEditFields(objectType string, values map[string]editValue, labels map[string]string) map[string]string {
+	problems := map[string]string{}
+	for name, rule := range fieldRulesFor(objectType) {
+		x, ok := values[name]
+		if !ok {
+			continue
+		}
+		text := strings.TrimSpace(x.text)
+		switch {
+		case rule.Required && text == "":
+			problems[name] = "Required"
+		case rule.MaxLength > 0 && len([]rune(text)) > rule.MaxLength:
+			problems[name] = fmt.Sprintf("At most %d characters, this is %d", rule.MaxLength, len([]rune(text)))
+		}
+	}
+	for name, x := range values {
+		if _, ok := problems[name]; ok {
+			continue
+		}
+		switch x.kind {
+		case "date":
+			if dateValidator(strings.TrimSpace(x.text)) != nil {
+				problems[name] = "Use a date like 2024-01-31"
+			}
+		case "select", "radio":
+			if !validChoice(name, x.text) {
+				problems[name] = fmt.Sprintf("%q is not one of the choices", x.text)
+			}
+		case "check":
+			for _, y := range x.chosen {
+				if !validChoice(name, y) {
+					problems[name] = fmt.Sprintf("%q is not one of the choices", y)
+					break
+				}
+			}
+		}
+		if name == "Links" {
+			for _, y := range azure.SplitLinks(x.text) {
+				if strings.TrimSpace(y) != "" && !validLink(y) {
+					problems[name] = fmt.Sprintf("%q should be Name (https://...)", strings.TrimSpace(y))
+					break
+				}
+			}
+		}
+	}
+	// Each lifecycle date can't be before the ones that come before it
+	var previous time.Time
+	previousName := ""
+	for _, name := range lifecycleDateOrder {
+		x, ok := values[name]
+		if !ok || problems[name] != "" {
+			continue
+		}
+		when, err := time.Parse("2006-01-02", strings.ReplaceAll(strings.TrimSpace(x.text), "/", "-"))
+		if err != nil {
+			continue
+		}
+		if previousName != "" && when.Before(previous) {
+			label := labels[previousName]
+			if label == "" {
+				label = previousName
+			}
+			problems[name] = fmt.Sprintf("Can't be before %s (%s)", label, previous.Format("2006-01-02"))
+			continue
+		}
+		previous, previousName = when, name
+	}
+	return problems
+}
+
+// Show each problem under its field and flag its tab; returns how many there are
+func showEditProblems(allFields modelFields, tabs *container.AppTabs, problems map[string]string) int {
+	_, tabFor := allFields.fieldLabels()
+	flagged := map[int]bool{}
+	for name, label := range allFields.errorLabels {
+		if problem, ok := problems[name]; ok {
+			label.SetText(problem)
+			label.Show()
+			flagged[tabFor[name]] = true
+		} else {
+			label.SetText("")
+			label.Hide()
+		}
+	}
+	first := -1
+	for i := range allFields.sections {
+		if i >= len(tabs.Items) {
+			continue
+		}
+		if flagged[i] {
+			tabs.Items[i].Icon = theme.ErrorIcon()
+			if first == -1 || i < first {
+				first = i
+			}
+		} else {
+			tabs.Items[i].Icon = nil
+		}
+	}
+	if first >= 0 && !flagged[tabs.SelectedIndex()] {
+		tabs.SelectIndex(first)
+	}
+	tabs.Refresh()
+	return len(problems)
+}
+
+func editProblemSummary(problems map[string]string, labels map[string]string) string {
+	lines := []string{}
+	for name, problem := range problems {
+		label := labels[name]
+		if label == "" {
+			label = name
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", label, problem))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+func newErrorLabel() *widget.Label {
+	label := widget.NewLabel("")
+	label.Importance = widget.DangerImportance
+	label.Wrapping = fyne.TextWrapWord
+	label.Hide()
+	return label
+}
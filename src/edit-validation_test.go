@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	azure "vonexplaino.com/m/v2/vondiagram/azure"
+)
+
+func TestValidateEditFields(t *testing.T) {
+	azure.ValidChoices["Lifecycle Status"] = map[string]string{"Live": "1", "Retired": "2"}
+	azure.ValidChoices["Categories"] = map[string]string{"Teaching": "1", "Research": "2"}
+	defer delete(azure.ValidChoices, "Lifecycle Status")
+	defer delete(azure.ValidChoices, "Categories")
+	pac := "Physical Application Component"
+	good := func() map[string]editValue {
+		return map[string]editValue{
+			"Title":            {kind: "string", text: "Canvas"},
+			"Description":      {kind: "string", text: "Learning management"},
+			"GU::Domain":       {kind: "select", text: "Learning"},
+			"Owner":            {kind: "select", text: "Pat"},
+			"Lifecycle Status": {kind: "select", text: "Live"},
+			"Links":            {kind: "string", text: "Home (https://example.com)\nDocs (http://example.com/docs)"},
+			"Categories":       {kind: "check", text: "Teaching", chosen: []string{"Teaching"}},
+			roadmapLive:        {kind: "date", text: "2020/01/01"},
+			roadmapRetirement:  {kind: "date", text: "2030-01-01"},
+		}
+	}
+	labels := map[string]string{roadmapLive: "Live"}
+	assert.Empty(t, validateEditFields(pac, good(), labels))
+
+	values := good()
+	values["Title"] = editValue{kind: "string", text: "  "}
+	values["Alias"] = editValue{kind: "string", text: strings.Repeat("a", 256)}
+	values["Lifecycle Status"] = editValue{kind: "select", text: "Dead"}
+	values["Categories"] = editValue{kind: "check", chosen: []string{"Teaching", "Sport"}}
+	values["Links"] = editValue{kind: "string", text: "Home (https://example.com), Wiki (wiki)"}
+	values[roadmapInDevelopment] = editValue{kind: "date", text: "31/01/2020"}
+	values[roadmapRetirement] = editValue{kind: "date", text: "2019-01-01"}
+	problems := validateEditFields(pac, values, labels)
+	assert.Equal(t, "Required", problems["Title"])
+	assert.Contains(t, problems["Alias"], "At most 255")
+	assert.Contains(t, problems["Lifecycle Status"], `"Dead"`)
+	assert.Contains(t, problems["Categories"], `"Sport"`)
+	assert.Contains(t, problems["Links"], "Wiki (wiki)")
+	assert.Contains(t, problems[roadmapInDevelopment], "Use a date")
+	assert.Equal(t, "Can't be before Live (2020-01-01)", problems[roadmapRetirement])
+	assert.Len(t, problems, 7)
+
+	// Only Title is required without a specific layout
+	assert.Equal(t, map[string]string{"Title": "Required"}, validateEditFields("Risk", map[string]editValue{
+		"Title":       {kind: "string"},
+		"Description": {kind: "string"},
+	}, nil))
+}
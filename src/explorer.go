@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	azure "vonexplaino.com/m/v2/vondiagram/azure"
+)
+
+/**
+** Explorer - walks out from the base object to find related components
+** to add to the diagram
+**/
+
+type explorerOptions struct {
+	depth        int
+	includeTypes map[string]bool
+	// Object types never walked to, even if included
+	excludeTypes  map[string]bool
+	skipRelations map[string]bool
+	// When set, only relationships with these lowercase names or directions are followed
+	onlyRelations map[string]bool
+	budget        int
+}
+
+type explorerResult struct {
+	relations map[string]azure.RelationStruct
+	// Relationships found from each relationship, keyed "" for the root, ready for the relationship tree
+	children  map[string][]string
+	depths    map[string]int
+	fetched   map[string]bool
+	truncated bool
+}
+
+// Breadth first walk from the root, following relationships to allowed object types until
+// the depth or node budget runs out
+func exploreFrom(rootId string, options explorerOptions, fetch func(string) []azure.RelationStruct) explorerResult {
+	result := explorerResult{
+		relations: map[string]azure.RelationStruct{},
+		children:  map[string][]string{},
+		depths:    map[string]int{rootId: 0},
+		fetched:   map[string]bool{},
+	}
+	type queued struct {
+		objectId string
+		via      string
+	}
+	queue := []queued{{objectId: rootId, via: ""}}
+	for len(queue) > 0 {
+		here := queue[0]
+		queue = queue[1:]
+		if result.depths[here.objectId] >= options.depth {
+			continue
+		}
+		result.fetched[here.objectId] = true
+		for _, x := range fetch(here.objectId) {
+			if _, seen := result.relations[x.RelationshipId]; seen {
+				continue
+			}
+			if options.skipRelations[strings.ToLower(x.RelationshipType.Name)] || options.skipRelations[strings.ToLower(x.RelationshipType.LeadToMemberDirection)] {
+				continue
+			}
+			if len(options.onlyRelations) > 0 && !options.onlyRelations[strings.ToLower(x.RelationshipType.Name)] && !options.onlyRelations[strings.ToLower(x.RelationshipType.LeadToMemberDirection)] {
+				continue
+			}
+			farId, far := x.MemberObjectId, x.MemberObject
+			if farId == here.objectId {
+				farId, far = x.LeadObjectId, x.LeadObject
+			}
+			if len(options.includeTypes) > 0 && !options.includeTypes[far.Type.Name] {
+				continue
+			}
+			if options.excludeTypes[far.Type.Name] {
+				continue
+			}
+			if _, seen := result.depths[farId]; !seen {
+				if options.budget > 0 && len(result.depths) >= options.budget {
+					result.truncated = true
+					continue
+				}
+				result.depths[farId] = result.depths[here.objectId] + 1
+				queue = append(queue, queued{objectId: farId, via: x.RelationshipId})
+			}
+			result.relations[x.RelationshipId] = x
+			result.children[here.via] = append(result.children[here.via], x.RelationshipId)
+		}
+	}
+	return result
+}
+
+// Ask for depth, types and budget, then hand the explored subgraph back for selection
+func showExplorerWindow(basics azure.IServerObjectStruct, thenWindow *fyne.Window, putInto func(explorerResult)) {
+	explorerWindow := addWindowFor("Explore relationships", 500, 600)
+	depth := widget.NewSelect([]string{"1", "2", "3", "4", "5"}, func(s string) {})
+	depth.SetSelected("2")
+	typeNames := []string{}
+	for _, x := range azure.ObjectTypesListLookup {
+		typeNames = append(typeNames, x)
+	}
+	sort.Strings(typeNames)
+	types := widget.NewCheckGroup(typeNames, func(s []string) {})
+	types.SetSelected([]string{
+		"Physical Application Component",
+		"Physical Technology Component",
+		"Physical Data Component",
+	})
+	skipTypes := widget.NewCheckGroup(typeNames, func(s []string) {})
+	skip := widget.NewEntry()
+	skip.SetPlaceHolder("is owned by, is responsible for")
+	budget := widget.NewEntry()
+	budget.SetText("50")
+	explorerWindow.SetContent(container.NewBorder(
+		nil,
+		widget.NewButton("Explore", func() {
+			options := explorerOptions{
+				includeTypes:  map[string]bool{},
+				excludeTypes:  map[string]bool{},
+				skipRelations: map[string]bool{},
+			}
+			options.depth, _ = strconv.Atoi(depth.Selected)
+			var err error
+			if options.budget, err = strconv.Atoi(budget.Text); err != nil {
+				dialog.ShowError(fmt.Errorf("node budget must be a number"), explorerWindow)
+				return
+			}
+			for _, x := range types.Selected {
+				options.includeTypes[x] = true
+			}
+			for _, x := range skipTypes.Selected {
+				options.excludeTypes[x] = true
+			}
+			for _, x := range strings.Split(skip.Text, ",") {
+				if len(strings.TrimSpace(x)) > 0 {
+					options.skipRelations[strings.ToLower(strings.TrimSpace(x))] = true
+				}
+			}
+			explorerWindow.Close()
+			UpdateMessage("Exploring")
+			go func() {
+				result := exploreFrom(basics.ObjectId, options, az.FindRelations)
+				UpdateMessage("Ready")
+				putInto(result)
+				if result.truncated {
+					dialog.ShowInformation("Explorer", fmt.Sprintf("Stopped at the node budget of %d objects", options.budget), *thenWindow)
+				}
+			}()
+		}),
+		nil,
+		nil,
+		container.NewVScroll(widget.NewForm(
+			widget.NewFormItem("Depth", depth),
+			widget.NewFormItem("Node budget", budget),
+			widget.NewFormItem("Skip relationships", skip),
+			widget.NewFormItem("Object types", types),
+			widget.NewFormItem("Skip object types", skipTypes),
+		)),
+	))
+	explorerWindow.Show()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	azure "vonexplaino.com/m/v2/vondiagram/azure"
+)
+
+func testRelation(id, lead, leadType, verb, member, memberType string) azure.RelationStruct {
+	x := azure.RelationStruct{RelationshipId: id, LeadObjectId: lead, MemberObjectId: member}
+	x.LeadObject.Name = lead
+	x.LeadObject.Type.Name = leadType
+	x.MemberObject.Name = member
+	x.MemberObject.Type.Name = memberType
+	x.RelationshipType.Name = verb
+	x.RelationshipType.LeadToMemberDirection = verb
+	return x
+}
+
+func TestExploreFrom(t *testing.T) {
+	pac := "Physical Application Component"
+	ptc := "Physical Technology Component"
+	org := "Organization Unit"
+	rels := []azure.RelationStruct{
+		testRelation("r1", "A", pac, "uses", "B", pac),
+		testRelation("r2", "B", pac, "is hosted on", "C", ptc),
+		testRelation("r3", "C", ptc, "uses", "D", pac),
+		testRelation("r4", "A", pac, "is owned by", "O", org),
+		testRelation("r5", "B", pac, "is owned by", "O", org),
+	}
+	calls := map[string]int{}
+	fetch := func(id string) []azure.RelationStruct {
+		calls[id]++
+		toReturn := []azure.RelationStruct{}
+		for _, x := range rels {
+			if x.LeadObjectId == id || x.MemberObjectId == id {
+				toReturn = append(toReturn, x)
+			}
+		}
+		return toReturn
+	}
+
+	result := exploreFrom("A", explorerOptions{depth: 2, skipRelations: map[string]bool{"is owned by": true}}, fetch)
+	assert.ElementsMatch(t, []string{"r1", "r2"}, getMapRelationKeys(result.relations))
+	assert.Equal(t, []string{"r1"}, result.children[""])
+	assert.Equal(t, []string{"r2"}, result.children["r1"])
+	assert.Equal(t, 2, result.depths["C"])
+	for id, n := range calls {
+		assert.Equal(t, 1, n, "fetched %s more than once", id)
+	}
+
+	result = exploreFrom("A", explorerOptions{depth: 5, includeTypes: map[string]bool{pac: true}}, fetch)
+	assert.ElementsMatch(t, []string{"r1"}, getMapRelationKeys(result.relations))
+
+	result = exploreFrom("A", explorerOptions{depth: 5, excludeTypes: map[string]bool{ptc: true, org: true}}, fetch)
+	assert.ElementsMatch(t, []string{"r1"}, getMapRelationKeys(result.relations))
+	assert.NotContains(t, result.depths, "C")
+
+	// Only the listed relationship types are followed
+	result = exploreFrom("A", explorerOptions{depth: 5, onlyRelations: map[string]bool{"uses": true}}, fetch)
+	assert.ElementsMatch(t, []string{"r1"}, getMapRelationKeys(result.relations))
+	assert.NotContains(t, result.depths, "C")
+
+	result = exploreFrom("A", explorerOptions{depth: 5, budget: 3}, fetch)
+	assert.True(t, result.truncated)
+	assert.LessOrEqual(t, len(result.depths), 3)
+}
+
+func getMapRelationKeys(me map[string]azure.RelationStruct) []string {
+	toReturn := []string{}
+	for i := range me {
+		toReturn = append(toReturn, i)
+	}
+	return toReturn
+}
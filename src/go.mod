@@ -7,6 +7,7 @@ toolchain go1.22.6
 require (
 	fyne.io/fyne/v2 v2.5.0
 	github.com/pkg/browser v0.0.0-20210911075715-681adbf594b8
+	github.com/stretchr/testify v1.8.4
 	github.com/xuri/excelize/v2 v2.8.1
 )
 
@@ -35,7 +36,6 @@ require (
 	github.com/rymdport/portal v0.2.2 // indirect
 	github.com/srwiley/oksvg v0.0.0-20221011165216-be6e8873101c // indirect
 	github.com/srwiley/rasterx v0.0.0-20220730225603-2ab79fcdd4ef // indirect
-	github.com/stretchr/testify v1.8.4 // indirect
 	github.com/xuri/efp v0.0.0-20231025114914-d1ff6096ae53 // indirect
 	github.com/xuri/nfp v0.0.0-20230919160717-d98342af3f05 // indirect
 	github.com/yuin/goldmark v1.7.1 // indirect
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/xuri/excelize/v2"
+	azure "vonexplaino.com/m/v2/vondiagram/azure"
+)
+
+/**
+** HERM coverage - compares the domain's PAC to Capability links against the
+** CAUDIT HERM business capability model, to find gaps, single points of
+** failure and overlaps
+**/
+
+//go:embed herm-coverage.html
+var hermCoverageTemplate string
+
+// Capabilities supported by this many applications or more are flagged for rationalisation
+var hermOverlapThreshold = 4
+
+type hermCapability struct {
+	Code   string `json:"Code"`
+	Group  string `json:"Group"`
+	Family string `json:"Family"`
+	Name   string `json:"Name"`
+}
+
+type hermCoverage struct {
+	hermCapability
+	Matched      []string
+	Applications []string
+	Rating       string
+}
+
+// No HERM model is bundled, as coverage against part of it would read as complete
+var errNoHERMModel = errors.New("set the CAUDIT HERM model (CSV or JSON) in Settings to report capability coverage")
+
+// Load the HERM model from a CSV or JSON export of CAUDIT HERM
+func loadHERMModel(fileName string) ([]hermCapability, error) {
+	model := []hermCapability{}
+	if fileName == "" {
+		return model, errNoHERMModel
+	}
+	f, err := os.Open(fileName)
+	if err != nil {
+		return model, err
+	}
+	defer f.Close()
+	if strings.EqualFold(filepath.Ext(fileName), ".csv") {
+		return parseHERMCSV(f)
+	}
+	contents, err := io.ReadAll(f)
+	if err != nil {
+		return model, err
+	}
+	err = json.Unmarshal(contents, &model)
+	return model, err
+}
+
+// Read a HERM CSV export, finding the columns by their headings
+func parseHERMCSV(r io.Reader) ([]hermCapability, error) {
+	model := []hermCapability{}
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return model, err
+	}
+	if len(rows) == 0 {
+		return model, fmt.Errorf("the HERM model file is empty")
+	}
+	columns := map[string]int{"code": -1, "name": -1, "family": -1, "group": -1}
+	aliases := map[string]string{
+		"code":       "code",
+		"id":         "code",
+		"name":       "name",
+		"capability": "name",
+		"family":     "family",
+		"parent":     "family",
+		"group":      "group",
+		"domain":     "group",
+	}
+	for i, x := range rows[0] {
+		if column, ok := aliases[strings.ToLower(strings.TrimSpace(x))]; ok && columns[column] == -1 {
+			columns[column] = i
+		}
+	}
+	if columns["name"] == -1 {
+		return model, fmt.Errorf("the HERM model file needs a Name or Capability column")
+	}
+	cell := func(row []string, column string) string {
+		if columns[column] == -1 || columns[column] >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[columns[column]])
+	}
+	for _, row := range rows[1:] {
+		if cell(row, "name") == "" {
+			continue
+		}
+		model = append(model, hermCapability{
+			Code:   cell(row, "code"),
+			Group:  cell(row, "group"),
+			Family: cell(row, "family"),
+			Name:   cell(row, "name"),
+		})
+	}
+	return model, nil
+}
+
+var capabilityNameNoise = regexp.MustCompile(`[^a-z0-9]`)
+
+func normaliseCapabilityName(name string) string {
+	return capabilityNameNoise.ReplaceAllString(strings.ReplaceAll(strings.ToLower(name), "&", "and"), "")
+}
+
+// Map iServer Capabilities onto the HERM model, by name or by code, and count the PACs behind each.
+// Also returns the iServer Capabilities that didn't match anything in the model.
+func hermCapabilityCoverage(model []hermCapability, objs []azure.ObjectStruct, lnks []azure.MinRelationship) ([]hermCoverage, []string) {
+	indexedObjs := map[string]azure.ObjectStruct{}
+	for _, x := range objs {
+		indexedObjs[x.ObjectID] = x
+	}
+	// PACs linked to each iServer capability
+	supporters := map[string]map[string]bool{}
+	for _, ln := range lnks {
+		lead, member := indexedObjs[ln.LeadObjectID], indexedObjs[ln.MemberObjectID]
+		if lead.ObjectType.Name == "Capability" {
+			lead, member = member, lead
+		}
+		if member.ObjectType.Name != "Capability" || lead.ObjectType.Name != "Physical Application Component" {
+			continue
+		}
+		if _, ok := supporters[member.ObjectID]; !ok {
+			supporters[member.ObjectID] = map[string]bool{}
+		}
+		supporters[member.ObjectID][lead.Name] = true
+	}
+	capabilities := []azure.ObjectStruct{}
+	for _, x := range objs {
+		if x.ObjectType.Name == "Capability" {
+			capabilities = append(capabilities, x)
+		}
+	}
+	sort.Slice(capabilities, func(i, j int) bool { return capabilities[i].Name < capabilities[j].Name })
+
+	mapped := map[string]bool{}
+	toReturn := []hermCoverage{}
+	for _, x := range model {
+		row := hermCoverage{hermCapability: x, Matched: []string{}, Applications: []string{}}
+		wanted := normaliseCapabilityName(x.Name)
+		applications := map[string]bool{}
+		for _, y := range capabilities {
+			have := normaliseCapabilityName(y.Name)
+			if have == wanted || (len(x.Code) > 0 && strings.EqualFold(strings.TrimSpace(y.Name), x.Code)) {
+				mapped[y.ObjectID] = true
+				row.Matched = append(row.Matched, y.Name)
+				for app := range supporters[y.ObjectID] {
+					applications[app] = true
+				}
+			}
+		}
+		for app := range applications {
+			row.Applications = append(row.Applications, app)
+		}
+		sort.Strings(row.Applications)
+		switch {
+		case len(row.Applications) == 0:
+			row.Rating = "gap"
+		case len(row.Applications) == 1:
+			row.Rating = "single"
+		case len(row.Applications) >= hermOverlapThreshold:
+			row.Rating = "overlap"
+		default:
+			row.Rating = "ok"
+		}
+		toReturn = append(toReturn, row)
+	}
+	unmapped := []string{}
+	for _, y := range capabilities {
+		if !mapped[y.ObjectID] {
+			unmapped = append(unmapped, y.Name)
+		}
+	}
+	return toReturn, unmapped
+}
+
+// The model the coverage was measured against, so a partial one is plain to see
+func hermModelDescription(modelFile string, coverage []hermCoverage) string {
+	return fmt.Sprintf("%d HERM capabilities from %s", len(coverage), filepath.Base(modelFile))
+}
+
+func createHERMCoverageHTML(domain, modelFile string, coverage []hermCoverage, unmapped []string) string {
+	type family struct {
+		Name         string
+		Capabilities []hermCoverage
+	}
+	type group struct {
+		Name     string
+		Families []*family
+	}
+	groups := []*group{}
+	for _, x := range coverage {
+		if len(groups) == 0 || groups[len(groups)-1].Name != x.Group {
+			groups = append(groups, &group{Name: x.Group})
+		}
+		g := groups[len(groups)-1]
+		if len(g.Families) == 0 || g.Families[len(g.Families)-1].Name != x.Family {
+			g.Families = append(g.Families, &family{Name: x.Family})
+		}
+		f := g.Families[len(g.Families)-1]
+		f.Capabilities = append(f.Capabilities, x)
+	}
+	tmpl, err := template.New("coverage").Funcs(template.FuncMap{"join": strings.Join}).Parse(hermCoverageTemplate)
+	if err != nil {
+		panic(err)
+	}
+	buf := bytes.NewBufferString("")
+	err = tmpl.Execute(buf, struct {
+		Domain   string
+		Model    string
+		Overlap  int
+		Groups   []*group
+		Unmapped []string
+	}{domain, hermModelDescription(modelFile, coverage), hermOverlapThreshold, groups, unmapped})
+	if err != nil {
+		panic(err)
+	}
+	return buf.String()
+}
+
+func createHERMCoverageExcel(modelFile string, coverage []hermCoverage, unmapped []string, fileName string) error {
+	f := excelize.NewFile()
+	defer f.Close()
+	sheet := "Coverage"
+	f.SetSheetName("Sheet1", sheet)
+	header, err := f.NewStyle(&excelize.Style{
+		Border: []excelize.Border{{Type: "bottom", Color: "000000", Style: 3}},
+		Font:   &excelize.Font{Bold: true},
+	})
+	if err != nil {
+		return err
+	}
+	fills := map[string]string{"gap": "F8696B", "single": "FFEB84", "ok": "63BE7B", "overlap": "8EA9DB"}
+	ratingStyles := map[string]int{}
+	for rating, colour := range fills {
+		if ratingStyles[rating], err = f.NewStyle(&excelize.Style{
+			Fill: excelize.Fill{Type: "pattern", Color: []string{colour}, Pattern: 1},
+		}); err != nil {
+			return err
+		}
+	}
+	wrap, err := f.NewStyle(&excelize.Style{Alignment: &excelize.Alignment{WrapText: true, Vertical: "top"}})
+	if err != nil {
+		return err
+	}
+	f.SetSheetRow(sheet, "A1", &[]interface{}{"Group", "Family", "Code", "HERM capability", "iServer capabilities", "Applications", "Supported by", "Rating"})
+	f.SetCellStyle(sheet, "A1", "H1", header)
+	f.SetColWidth(sheet, "A", "B", 30)
+	f.SetColWidth(sheet, "D", "E", 40)
+	f.SetColWidth(sheet, "G", "G", 60)
+	for i, x := range coverage {
+		row := i + 2
+		cell, _ := excelize.CoordinatesToCellName(1, row)
+		f.SetSheetRow(sheet, cell, &[]interface{}{
+			x.Group,
+			x.Family,
+			x.Code,
+			x.Name,
+			strings.Join(x.Matched, "\n"),
+			len(x.Applications),
+			strings.Join(x.Applications, "\n"),
+			x.Rating,
+		})
+		start, _ := excelize.CoordinatesToCellName(1, row)
+		end, _ := excelize.CoordinatesToCellName(7, row)
+		f.SetCellStyle(sheet, start, end, wrap)
+		rating, _ := excelize.CoordinatesToCellName(8, row)
+		f.SetCellStyle(sheet, rating, rating, ratingStyles[x.Rating])
+	}
+	last, _ := excelize.CoordinatesToCellName(8, len(coverage)+1)
+	if err := f.AddTable(sheet, &excelize.Table{Range: "A1:" + last}); err != nil {
+		return err
+	}
+
+	f.NewSheet("Unmapped")
+	f.SetCellValue("Unmapped", "A1", "iServer capabilities not in the HERM model")
+	f.SetCellStyle("Unmapped", "A1", "A1", header)
+	f.SetColWidth("Unmapped", "A", "A", 60)
+	for i, x := range unmapped {
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		f.SetCellValue("Unmapped", cell, x)
+	}
+
+	f.NewSheet("Model")
+	f.SetCellValue("Model", "A1", "Measured against")
+	f.SetCellStyle("Model", "A1", "A1", header)
+	f.SetCellValue("Model", "A2", hermModelDescription(modelFile, coverage))
+	f.SetColWidth("Model", "A", "A", 80)
+	return f.SaveAs(fileName)
+}
@@ -2,11 +2,18 @@ package main
 
 import (
 	"bytes"
-	_ "embed"
+	"embed"
+	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"text/template"
 
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
 	azure "vonexplaino.com/m/v2/vondiagram/azure"
 )
 
@@ -15,32 +22,260 @@ import (
 ** Uses D3 to create a force directed graph of relationships
 **/
 
+//go:generate curl -sSLo herm-assets/d3.v6.min.js https://d3js.org/d3.v6.min.js
+
 //go:embed force-graph.html
 var tmplFile string
 
-func CreateHERM() {
+//go:embed herm-assets
+var hermAssets embed.FS
+
+// Scripts the report needs, inlined when they've been fetched into herm-assets
+var hermScripts = []struct {
+	file string
+	cdn  string
+}{
+	{"d3.v6.min.js", `<script src="https://d3js.org/d3.v6.min.js"></script>`},
+}
+
+// Object types the HERM report can follow out from the domain's PACs
+var hermObjectTypes = []string{
+	"Capability",
+	"Logical Application Component",
+	"Logical Data Component",
+	"Logical Technology Component",
+	"Physical Data Component",
+	"Physical Technology Component",
+}
+
+// Types reached a hop out from the PACs, through the type they're linked to
+var hermSecondHops = map[string]string{
+	"Logical Application Component": "Logical Technology Component",
+	"Physical Data Component":       "Logical Data Component",
+}
+
+// Link the PACs straight to the LTCs and LDCs found through LACs and PDCs that weren't chosen,
+// leaving those LACs and PDCs out of the report
+func hideHERMIntermediates(objs []azure.ObjectStruct, lnks []azure.MinRelationship, relatedTypes []string) ([]azure.ObjectStruct, []azure.MinRelationship) {
+	hiddenTypes := map[string]string{}
+	for via, far := range hermSecondHops {
+		if slices.Contains(relatedTypes, far) && !slices.Contains(relatedTypes, via) {
+			hiddenTypes[via] = far
+		}
+	}
+	if len(hiddenTypes) == 0 {
+		return objs, lnks
+	}
+	typeOf := map[string]string{}
+	for _, x := range objs {
+		typeOf[x.ObjectID] = x.ObjectType.Name
+	}
+	neighbours := map[string][]string{}
+	for _, x := range lnks {
+		neighbours[x.LeadObjectID] = append(neighbours[x.LeadObjectID], x.MemberObjectID)
+		neighbours[x.MemberObjectID] = append(neighbours[x.MemberObjectID], x.LeadObjectID)
+	}
+	toReturnObjects := []azure.ObjectStruct{}
+	for _, x := range objs {
+		if _, hidden := hiddenTypes[x.ObjectType.Name]; !hidden {
+			toReturnObjects = append(toReturnObjects, x)
+		}
+	}
+	toReturnLinks := []azure.MinRelationship{}
+	for _, x := range lnks {
+		_, leadHidden := hiddenTypes[typeOf[x.LeadObjectID]]
+		_, memberHidden := hiddenTypes[typeOf[x.MemberObjectID]]
+		if !leadHidden && !memberHidden {
+			toReturnLinks = append(toReturnLinks, x)
+		}
+	}
+	linked := map[string]bool{}
+	for _, x := range objs {
+		far, hidden := hiddenTypes[x.ObjectType.Name]
+		if !hidden {
+			continue
+		}
+		for _, nearId := range neighbours[x.ObjectID] {
+			if typeOf[nearId] != "Physical Application Component" {
+				continue
+			}
+			for _, farId := range neighbours[x.ObjectID] {
+				if typeOf[farId] == far && !linked[nearId+"|"+farId] {
+					linked[nearId+"|"+farId] = true
+					toReturnLinks = append(toReturnLinks, azure.MinRelationship{LeadObjectID: nearId, MemberObjectID: farId, LeadToMemberDirection: "through " + x.Name})
+				}
+			}
+		}
+	}
+	return toReturnObjects, toReturnLinks
+}
+
+// Build the HERM report for a domain, plus the capability coverage when Capabilities are included,
+// returning the files written
+func CreateHERM(domain, savePath string, relatedTypes []string) ([]string, error) {
+	written := []string{}
 	// Download iServer data
-	objects := az.GetDomainObjectsForHERM()
+	objects := az.GetDomainObjectsForHERM(domain)
+	if len(objects) == 0 {
+		return written, fmt.Errorf("no live PACs found in %s", domain)
+	}
 	// Get relationships
-	objects, relations := az.GetRelatedHERMObjects(objects)
-	// Convert into the D3 expected format
+	objects, relations := az.GetRelatedHERMObjects(objects, relatedTypes)
+	objects, relations = hideHERMIntermediates(objects, relations, relatedTypes)
 	// Save to HTML
-	os.WriteFile("/Users/s457972/Dropbox/swap/golang/von-iserver-diagram/src/force-graph-out.html", []byte(createHERMHTML(objects, relations)), 0644)
+	fileBase := filepath.Join(savePath, fmt.Sprintf("HERM %s", strings.NewReplacer("/", "-", "\\", "-", ":", "-").Replace(domain)))
+	if err := os.WriteFile(fileBase+".html", []byte(createHERMHTML(objects, relations)), 0644); err != nil {
+		return written, err
+	}
+	written = append(written, fileBase+".html")
+	if !slices.Contains(relatedTypes, "Capability") {
+		return written, nil
+	}
+	modelFile := myApp.Preferences().StringWithFallback("HERMModel", "")
+	model, err := loadHERMModel(modelFile)
+	if err != nil {
+		return written, err
+	}
+	coverage, unmapped := hermCapabilityCoverage(model, objects, relations)
+	if err := os.WriteFile(fileBase+" coverage.html", []byte(createHERMCoverageHTML(domain, modelFile, coverage, unmapped)), 0644); err != nil {
+		return written, err
+	}
+	written = append(written, fileBase+" coverage.html")
+	if err := createHERMCoverageExcel(modelFile, coverage, unmapped, fileBase+" coverage.xlsx"); err != nil {
+		return written, err
+	}
+	return append(written, fileBase+" coverage.xlsx"), nil
+}
+
+// Choose the object types to report on, then build the report for the Settings department and open it
+func showHERMDialog(thenWindow fyne.Window) {
+	domain := myApp.Preferences().StringWithFallback("Department", "")
+	if domain == "" {
+		dialog.ShowInformation("HERM", "Choose a domain in Settings first", thenWindow)
+		return
+	}
+	types := widget.NewCheckGroup(hermObjectTypes, func(s []string) {})
+	types.SetSelected(myApp.Preferences().StringListWithFallback("HERMTypes", hermObjectTypes))
+	dialog.ShowForm(
+		"HERM report for "+domain,
+		"Create",
+		"Cancel",
+		[]*widget.FormItem{widget.NewFormItem("Include", types)},
+		func(ok bool) {
+			if !ok {
+				return
+			}
+			if slices.Contains(types.Selected, "Capability") && myApp.Preferences().StringWithFallback("HERMModel", "") == "" {
+				dialog.ShowInformation("HERM", "Set the CAUDIT HERM model in Settings to report capability coverage", thenWindow)
+				return
+			}
+			myApp.Preferences().SetStringList("HERMTypes", types.Selected)
+			UpdateMessage("Running")
+			go func() {
+				written, err := CreateHERM(domain, getSavePath(), types.Selected)
+				UpdateMessage("Done")
+				if err != nil {
+					dialog.ShowError(err, thenWindow)
+					return
+				}
+				for _, x := range written {
+					if filepath.Ext(x) != ".html" {
+						continue
+					}
+					location := filepath.ToSlash(x)
+					if !strings.HasPrefix(location, "/") {
+						location = "/" + location
+					}
+					openbrowser("file://" + location)
+				}
+			}()
+		},
+		thenWindow,
+	)
+}
+
+type hermNode struct {
+	Id        string `json:"id"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Lifecycle string `json:"lifecycle"`
+	URL       string `json:"url"`
+}
+
+type hermLink struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Type   string `json:"type"`
+}
+
+// Nodes and links for the force graph, with what the report filters and searches on
+func hermGraphJSON(objs []azure.ObjectStruct, lnks []azure.MinRelationship) string {
+	graph := struct {
+		Nodes []hermNode `json:"nodes"`
+		Links []hermLink `json:"links"`
+	}{[]hermNode{}, []hermLink{}}
+	known := map[string]bool{}
+	for _, ob := range objs {
+		known[ob.ObjectID] = true
+		lifecycle := "Unknown"
+		for _, x := range ob.Attributevalues {
+			if x.AttributeName == "Lifecycle Status" && len(x.StringValue) > 0 {
+				lifecycle = x.StringValue
+			}
+		}
+		graph.Nodes = append(graph.Nodes, hermNode{
+			Id:        ob.ObjectID,
+			Name:      ob.Name,
+			Type:      ob.ObjectType.Name,
+			Lifecycle: lifecycle,
+			URL:       fmt.Sprintf(iServerObjectURL, ob.ObjectID),
+		})
+	}
+	for _, ln := range lnks {
+		// D3 refuses to draw links to nodes it doesn't have
+		if !known[ln.LeadObjectID] || !known[ln.MemberObjectID] {
+			continue
+		}
+		relType := ln.LeadToMemberDirection
+		if relType == "" {
+			relType = ln.RelationshipTypeName
+		}
+		graph.Links = append(graph.Links, hermLink{Source: ln.LeadObjectID, Target: ln.MemberObjectID, Type: relType})
+	}
+	asJson, err := json.Marshal(graph)
+	if err != nil {
+		panic(err)
+	}
+	return string(asJson)
+}
+
+func hermScriptTags() string {
+	toReturn := new(strings.Builder)
+	for _, x := range hermScripts {
+		if js, err := hermAssets.ReadFile("herm-assets/" + x.file); err == nil {
+			toReturn.WriteString("<script>\n")
+			toReturn.Write(js)
+			toReturn.WriteString("\n</script>\n")
+		} else {
+			toReturn.WriteString(x.cdn + "\n")
+		}
+	}
+	return toReturn.String()
 }
 
 func createHERMHTML(objs []azure.ObjectStruct, lnks []azure.MinRelationship) string {
 	// Setup variables
 	type graphicStruct struct {
-		OBJS    []azure.ObjectStruct
-		LNKS    []azure.MinRelationship
+		SCRIPTS string
+		GRAPH   string
 		PACRELS map[string]struct {
 			Name      string
 			Relations map[string]string
 		}
 	}
 	gs := graphicStruct{
-		OBJS: objs,
-		LNKS: lnks,
+		SCRIPTS: hermScriptTags(),
+		GRAPH:   hermGraphJSON(objs, lnks),
 		PACRELS: map[string]struct {
 			Name      string
 			Relations map[string]string
@@ -60,16 +295,38 @@ func createHERMHTML(objs []azure.ObjectStruct, lnks []azure.MinRelationship) str
 					"Capability":                    "<br>",
 					"Physical Data Component":       "<br>",
 					"Logical Application Component": "<br>",
+					"Logical Technology Component":  "<br>",
+					"Logical Data Component":        "<br>",
 				},
 			}
 		}
 	}
+	neighbours := map[string][]string{}
 	for _, ln := range lnks {
-		if _, ok := gs.PACRELS[ln.LeadObjectID]; ok {
-			gs.PACRELS[ln.LeadObjectID].Relations[indexedObjs[ln.MemberObjectID].ObjectType.Name] = fmt.Sprintf("%s%s<br>", gs.PACRELS[ln.LeadObjectID].Relations[indexedObjs[ln.MemberObjectID].ObjectType.Name], indexedObjs[ln.MemberObjectID].Name)
-		}
-		if _, ok := gs.PACRELS[ln.MemberObjectID]; ok {
-			gs.PACRELS[ln.MemberObjectID].Relations[indexedObjs[ln.LeadObjectID].ObjectType.Name] = fmt.Sprintf("%s%s<br>", gs.PACRELS[ln.MemberObjectID].Relations[indexedObjs[ln.LeadObjectID].ObjectType.Name], indexedObjs[ln.LeadObjectID].Name)
+		neighbours[ln.LeadObjectID] = append(neighbours[ln.LeadObjectID], ln.MemberObjectID)
+		neighbours[ln.MemberObjectID] = append(neighbours[ln.MemberObjectID], ln.LeadObjectID)
+	}
+	// The LTCs and LDCs are a hop away, through the PAC's LACs and PDCs
+	for pacId, pac := range gs.PACRELS {
+		seen := map[string]bool{}
+		for _, nearId := range neighbours[pacId] {
+			near := indexedObjs[nearId]
+			if !seen[nearId] {
+				seen[nearId] = true
+				pac.Relations[near.ObjectType.Name] = fmt.Sprintf("%s%s<br>", pac.Relations[near.ObjectType.Name], near.Name)
+			}
+			farType, ok := hermSecondHops[near.ObjectType.Name]
+			if !ok {
+				continue
+			}
+			for _, farId := range neighbours[nearId] {
+				far := indexedObjs[farId]
+				if far.ObjectType.Name != farType || seen[farId] {
+					continue
+				}
+				seen[farId] = true
+				pac.Relations[farType] = fmt.Sprintf("%s%s<br>", pac.Relations[farType], far.Name)
+			}
 		}
 	}
 
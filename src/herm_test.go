@@ -1,6 +1,8 @@
 package main
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -21,13 +23,108 @@ func TestCreateHERMHTML(t *testing.T) {
 			Name:            "PAC2",
 			ObjectTypeId:    "6fb624e4-b642-ea11-a601-28187852aafd",
 			ObjectType:      azure.ObjectTypeStruct{Name: "Physical Application Component"},
-			Attributevalues: []azure.AttributeTypeStruct{},
+			Attributevalues: []azure.AttributeTypeStruct{{AttributeName: "Lifecycle Status", StringValue: "Live"}},
 		},
 	}
 	lnks := []azure.MinRelationship{
-		{LeadObjectID: "1", MemberObjectID: "2"},
+		{LeadObjectID: "1", MemberObjectID: "2", LeadToMemberDirection: "uses"},
+		{LeadObjectID: "1", MemberObjectID: "3", LeadToMemberDirection: "uses"},
 	}
 	bob := createHERMHTML(objs, lnks)
 	assert.Contains(t, bob, `<html>`)
-	assert.Contains(t, bob, "const nodes = [\n            { id: \"1\", group: 1, name: \"PAC1\", type: \"Physical Application Component\" },{ id: \"2\", group: 1, name: \"PAC2\", type: \"Physical Application Component\" },\n        ];\n        const links = [\n            { source: \"1\", target: \"2\", value: \"9\" },\n            \n        ];")
+	// D3 is inlined once go generate has fetched it, and loaded from its CDN until then
+	if d3, err := hermAssets.ReadFile("herm-assets/d3.v6.min.js"); err == nil {
+		assert.Contains(t, bob, "<script>\n"+string(d3))
+		assert.NotContains(t, bob, "https://d3js.org")
+	} else {
+		assert.Contains(t, bob, `<script src="https://d3js.org/d3.v6.min.js"></script>`)
+	}
+	assert.Contains(t, bob, `const graph = {"nodes":[`+
+		`{"id":"1","name":"PAC1","type":"Physical Application Component","lifecycle":"Unknown","url":"https://griffith.iserver365.com/object/1/details"},`+
+		`{"id":"2","name":"PAC2","type":"Physical Application Component","lifecycle":"Live","url":"https://griffith.iserver365.com/object/2/details"}],`+
+		`"links":[{"source":"1","target":"2","type":"uses"}]};`)
+}
+
+func TestHERMCapabilityCoverage(t *testing.T) {
+	model, err := parseHERMCSV(strings.NewReader("Code,Capability,Family,Group\nBC001,Curriculum Planning,Curriculum Management,Learning & Teaching\nBC002,Timetabling,Teaching & Learning,Learning & Teaching\nBC003,Payroll,Human Resource Management,Enabling\nBC004,Management,Enterprise Management,Enabling\n"))
+	assert.NoError(t, err)
+	assert.Len(t, model, 4)
+	objs := []azure.ObjectStruct{
+		{ObjectID: "c1", Name: "Curriculum planning", ObjectType: azure.ObjectTypeStruct{Name: "Capability"}},
+		{ObjectID: "c2", Name: "bc003", ObjectType: azure.ObjectTypeStruct{Name: "Capability"}},
+		{ObjectID: "c3", Name: "Parking", ObjectType: azure.ObjectTypeStruct{Name: "Capability"}},
+		{ObjectID: "c4", Name: "Research Management", ObjectType: azure.ObjectTypeStruct{Name: "Capability"}},
+		{ObjectID: "c5", Name: "BC0011 Curriculum Review", ObjectType: azure.ObjectTypeStruct{Name: "Capability"}},
+	}
+	lnks := []azure.MinRelationship{}
+	for i := 1; i <= 4; i++ {
+		id := fmt.Sprintf("p%d", i)
+		objs = append(objs, azure.ObjectStruct{ObjectID: id, Name: "PAC" + id, ObjectType: azure.ObjectTypeStruct{Name: "Physical Application Component"}})
+		lnks = append(lnks, azure.MinRelationship{LeadObjectID: id, MemberObjectID: "c2"})
+	}
+	lnks = append(lnks, azure.MinRelationship{LeadObjectID: "c1", MemberObjectID: "p1"})
+	coverage, unmapped := hermCapabilityCoverage(model, objs, lnks)
+	assert.Equal(t, "single", coverage[0].Rating)
+	assert.Equal(t, []string{"Curriculum planning"}, coverage[0].Matched)
+	assert.Equal(t, "gap", coverage[1].Rating)
+	assert.Equal(t, "overlap", coverage[2].Rating)
+	assert.Len(t, coverage[2].Applications, 4)
+	assert.Empty(t, coverage[3].Matched)
+	assert.Equal(t, []string{"BC0011 Curriculum Review", "Parking", "Research Management"}, unmapped)
+
+	_, err = loadHERMModel("")
+	assert.ErrorIs(t, err, errNoHERMModel, "nothing is bundled to fall back on")
+	html := createHERMCoverageHTML("Test", "/models/herm.csv", coverage, unmapped)
+	assert.Contains(t, html, `class="capability overlap"`)
+	assert.Contains(t, html, "Measured against 4 HERM capabilities from herm.csv")
+}
+
+func TestCreateHERMHTMLSecondHop(t *testing.T) {
+	objs := []azure.ObjectStruct{
+		{ObjectID: "1", Name: "PAC1", ObjectType: azure.ObjectTypeStruct{Name: "Physical Application Component"}},
+		{ObjectID: "2", Name: "LAC1", ObjectType: azure.ObjectTypeStruct{Name: "Logical Application Component"}},
+		{ObjectID: "3", Name: "LTC1", ObjectType: azure.ObjectTypeStruct{Name: "Logical Technology Component"}},
+		{ObjectID: "4", Name: "PDC1", ObjectType: azure.ObjectTypeStruct{Name: "Physical Data Component"}},
+		{ObjectID: "5", Name: "LDC1", ObjectType: azure.ObjectTypeStruct{Name: "Logical Data Component"}},
+	}
+	lnks := []azure.MinRelationship{
+		{LeadObjectID: "1", MemberObjectID: "2"},
+		{LeadObjectID: "3", MemberObjectID: "2"},
+		{LeadObjectID: "4", MemberObjectID: "1"},
+		{LeadObjectID: "4", MemberObjectID: "5"},
+	}
+	bob := createHERMHTML(objs, lnks)
+	assert.Contains(t, bob, "<td>PAC1</td>\n                <td><br>LAC1<br></td>\n                <td><br>LTC1<br></td>\n                <td><br></td>\n                <td><br>PDC1<br></td>\n                <td><br>LDC1<br></td>")
+}
+
+func TestHideHERMIntermediates(t *testing.T) {
+	objs := []azure.ObjectStruct{
+		{ObjectID: "1", Name: "PAC1", ObjectType: azure.ObjectTypeStruct{Name: "Physical Application Component"}},
+		{ObjectID: "2", Name: "LAC1", ObjectType: azure.ObjectTypeStruct{Name: "Logical Application Component"}},
+		{ObjectID: "3", Name: "LTC1", ObjectType: azure.ObjectTypeStruct{Name: "Logical Technology Component"}},
+		{ObjectID: "4", Name: "PDC1", ObjectType: azure.ObjectTypeStruct{Name: "Physical Data Component"}},
+		{ObjectID: "5", Name: "LDC1", ObjectType: azure.ObjectTypeStruct{Name: "Logical Data Component"}},
+	}
+	lnks := []azure.MinRelationship{
+		{LeadObjectID: "1", MemberObjectID: "2"},
+		{LeadObjectID: "3", MemberObjectID: "2"},
+		{LeadObjectID: "4", MemberObjectID: "1"},
+		{LeadObjectID: "4", MemberObjectID: "5"},
+	}
+	shown, shownLinks := hideHERMIntermediates(objs, lnks, []string{"Logical Application Component", "Logical Data Component", "Physical Data Component"})
+	assert.Len(t, shown, 5, "nothing hidden when the LACs and PDCs are chosen too")
+	assert.Equal(t, lnks, shownLinks)
+
+	// Only the LTCs and LDCs chosen: still reached, without showing the LACs and PDCs
+	shown, shownLinks = hideHERMIntermediates(objs, lnks, []string{"Logical Technology Component", "Logical Data Component"})
+	names := []string{}
+	for _, x := range shown {
+		names = append(names, x.Name)
+	}
+	assert.Equal(t, []string{"PAC1", "LTC1", "LDC1"}, names)
+	assert.ElementsMatch(t, []azure.MinRelationship{
+		{LeadObjectID: "1", MemberObjectID: "3", LeadToMemberDirection: "through LAC1"},
+		{LeadObjectID: "1", MemberObjectID: "5", LeadToMemberDirection: "through PDC1"},
+	}, shownLinks)
+	assert.Contains(t, createHERMHTML(shown, shownLinks), "<td>PAC1</td>\n                <td><br></td>\n                <td><br>LTC1<br></td>\n                <td><br></td>\n                <td><br></td>\n                <td><br>LDC1<br></td>")
 }
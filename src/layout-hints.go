@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	azure "vonexplaino.com/m/v2/vondiagram/azure"
+)
+
+/**
+** Layout hints - the overall layout direction and Lay_ constraints between
+** elements, so generated diagrams don't need hand tuning after export
+**/
+
+// Objects that will appear on the diagram, labelled by id. Names shared by more
+// than one object get the object type added, and the id if that's shared too.
+func diagramObjectLabels(basics azure.IServerObjectStruct, selectedRelations map[string]azure.RelationStruct) (map[string]string, []string) {
+	root := azure.FindStruct{ObjectId: basics.ObjectId, Name: basics.Name}
+	root.Type.Name = basics.ObjectType.Name
+	objects := map[string]azure.FindStruct{basics.ObjectId: root}
+	for _, x := range selectedRelations {
+		lead, member := x.LeadObject, x.MemberObject
+		lead.ObjectId, member.ObjectId = x.LeadObjectId, x.MemberObjectId
+		for _, y := range []azure.FindStruct{lead, member} {
+			if _, ok := objects[y.ObjectId]; !ok || objects[y.ObjectId].Type.Name == "" {
+				objects[y.ObjectId] = y
+			}
+		}
+	}
+	named, typed := map[string]int{}, map[string]int{}
+	for _, x := range objects {
+		named[x.Name]++
+		typed[x.Name+"|"+x.Type.Name]++
+	}
+	labels := map[string]string{}
+	sorted := []string{}
+	for id, x := range objects {
+		label := x.Name
+		if typed[x.Name+"|"+x.Type.Name] > 1 {
+			label = fmt.Sprintf("%s (%s)", x.Name, id)
+		} else if named[x.Name] > 1 {
+			label = fmt.Sprintf("%s (%s)", x.Name, x.Type.Name)
+		}
+		labels[id] = label
+		sorted = append(sorted, label)
+	}
+	sort.Strings(sorted)
+	return labels, sorted
+}
+
+func showLayoutWindow(
+	basics azure.IServerObjectStruct,
+	selectedRelations map[string]azure.RelationStruct,
+	definition *diagramDefinition,
+	onChange func()) {
+	layoutWindow := addWindowFor("Diagram layout", 500, 400)
+	namesById, names := diagramObjectLabels(basics, selectedRelations)
+	ids := map[string]string{}
+	for id, x := range namesById {
+		ids[x] = id
+	}
+
+	mode := widget.NewSelect(append([]string{"Default"}, layoutModes...), func(s string) {})
+	if len(definition.LayoutMode) > 0 {
+		mode.SetSelected(definition.LayoutMode)
+	} else {
+		mode.SetSelected("Default")
+	}
+	mode.OnChanged = func(s string) {
+		definition.LayoutMode = s
+		if s == "Default" {
+			definition.LayoutMode = ""
+		}
+		onChange()
+	}
+
+	existing := container.NewVBox()
+	var drawExisting func()
+	drawExisting = func() {
+		existing.Objects = []fyne.CanvasObject{}
+		for i, x := range definition.Layouts {
+			me := i
+			fromName, toName := namesById[x.From], namesById[x.To]
+			if fromName == "" {
+				fromName = x.From
+			}
+			if toName == "" {
+				toName = x.To
+			}
+			existing.Add(container.NewBorder(
+				nil,
+				nil,
+				nil,
+				widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+					definition.Layouts = append(definition.Layouts[:me], definition.Layouts[me+1:]...)
+					drawExisting()
+					onChange()
+				}),
+				widget.NewLabel(fmt.Sprintf("Lay_%s(%s, %s)", x.Direction, fromName, toName)),
+			))
+		}
+		existing.Refresh()
+	}
+	drawExisting()
+
+	from := widget.NewSelect(names, func(s string) {})
+	direction := widget.NewSelect(relationDirections, func(s string) {})
+	direction.SetSelected("Down")
+	to := widget.NewSelect(names, func(s string) {})
+	layoutWindow.SetContent(container.NewBorder(
+		container.NewVBox(
+			widget.NewForm(
+				widget.NewFormItem("Layout", mode),
+				widget.NewFormItem("From", from),
+				widget.NewFormItem("Direction", direction),
+				widget.NewFormItem("To", to),
+			),
+			widget.NewButtonWithIcon("Add constraint", theme.ContentAddIcon(), func() {
+				if from.Selected == "" || to.Selected == "" || from.Selected == to.Selected {
+					dialog.ShowInformation("Layout", "Choose two different elements", layoutWindow)
+					return
+				}
+				definition.Layouts = append(definition.Layouts, layoutHint{
+					From:      ids[from.Selected],
+					To:        ids[to.Selected],
+					Direction: direction.Selected,
+				})
+				drawExisting()
+				onChange()
+			}),
+			widget.NewSeparator(),
+		),
+		nil,
+		nil,
+		nil,
+		container.NewVScroll(existing),
+	))
+	layoutWindow.Show()
+}
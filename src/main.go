@@ -52,6 +52,7 @@ type relationshipStruct struct {
 	leftAlias        string
 	rightAlias       string
 	relationshipName string
+	technology       string
 }
 
 func connectToAzure(dept *widget.Select) {
@@ -88,6 +89,13 @@ func main() {
 	// Basic window setup
 	windows = make(map[string]fyne.Window)
 	myApp = app.NewWithID("com.vonexplaino.voniserverdiagram")
+	applyEditForms()
+	if len(os.Args) > 2 && os.Args[1] == "regenerate" {
+		os.Exit(runRegenerateCLI(os.Args[2:]))
+	}
+	if len(os.Args) > 2 && os.Args[1] == "quality" {
+		os.Exit(runQualityCLI(os.Args[2:]))
+	}
 	status = binding.NewString()
 	messages = binding.NewString()
 	dept := widget.NewSelect([]string{}, func(change string) {})
@@ -140,7 +148,7 @@ func main() {
 							func() {
 								createEditWindow(
 									"New Physical Application Component",
-									newPACTemplate(PacFields()),
+									newObjectTemplate("Physical Application Component"),
 									[]azure.RelationStruct{},
 								)
 							},
@@ -150,12 +158,48 @@ func main() {
 							resourcePtcPng,
 							func() {
 								createEditWindow(
-									"New Physical Application Component",
-									newPTCTemplate(PtcFields()),
+									"New Physical Technology Component",
+									newObjectTemplate("Physical Technology Component"),
+									[]azure.RelationStruct{},
+								)
+							},
+						),
+						widget.NewButtonWithIcon(
+							"+LAC",
+							resourceLacPng,
+							func() {
+								createEditWindow(
+									"New Logical Application Component",
+									newObjectTemplate("Logical Application Component"),
+									[]azure.RelationStruct{},
+								)
+							},
+						),
+						widget.NewButtonWithIcon(
+							"+PDC",
+							objectTypeIcon("Physical Data Component"),
+							func() {
+								createEditWindow(
+									"New Physical Data Component",
+									newObjectTemplate("Physical Data Component"),
 									[]azure.RelationStruct{},
 								)
 							},
 						),
+						widget.NewButtonWithIcon(
+							"Regenerate",
+							theme.ViewRefreshIcon(),
+							func() {
+								showRegenerateDialog(mainWindow)
+							},
+						),
+						widget.NewButtonWithIcon(
+							"Reconcile",
+							theme.DocumentIcon(),
+							func() {
+								showReconcileDialog(mainWindow)
+							},
+						),
 					),
 					nil,
 					widget.NewLabel("Looking for"),
@@ -185,14 +229,22 @@ func main() {
 					UpdateMessage("Ready")
 				}),
 				widget.NewButton("Excel Audit", func() {
-					UpdateMessage("Running")
-					az.CreateProductManagerOverviewReport(myApp.Preferences().StringWithFallback("Department", "nope"), getSavePath())
-					UpdateMessage("Ready")
+					showDomainWorkbookDialog(mainWindow)
+				}),
+				widget.NewButton("Excel Import", func() {
+					showWorkbookImportDialog(mainWindow)
+				}),
+				widget.NewButton("Data quality", func() {
+					showQualityDialog(mainWindow)
 				}),
 				widget.NewButton("HERM", func() {
-					UpdateMessage("Running")
-					CreateHERM()
-					UpdateMessage("Done")
+					showHERMDialog(mainWindow)
+				}),
+				widget.NewButton("Roadmap", func() {
+					showRoadmapDialog(mainWindow)
+				}),
+				widget.NewButton("Relationship matrix", func() {
+					showRelationshipMatrixWindow()
 				}),
 			)),
 		container.NewTabItem(
@@ -217,89 +269,6 @@ func main() {
 	tidyUp()
 }
 
-func newPACTemplate(template modelFields) azure.IServerObjectStruct {
-	newObject := azure.IServerObjectStruct{
-		Name:     "",
-		ObjectId: "",
-		AttributeValues: []azure.AttributeValue{
-			{StringValue: "", AttributeName: "Owner"},
-		},
-		ObjectType: struct {
-			Name string `json:"Name"`
-			Id   string `json:"ObjectTypeId"`
-		}{Name: "Physical Application Component"},
-	}
-	for name := range template.selectValues {
-		azure.ValidChoices[name] = az.GetChoicesForName(name)
-		newObject.AttributeValues = append(
-			newObject.AttributeValues,
-			azure.AttributeValue{
-				AttributeName: name,
-			},
-		)
-	}
-	for name := range template.radioValues {
-		azure.ValidChoices[name] = az.GetChoicesForName(name)
-		newObject.AttributeValues = append(
-			newObject.AttributeValues,
-			azure.AttributeValue{
-				AttributeName: name,
-			},
-		)
-	}
-	for name := range template.checkValues {
-		azure.ValidChoices[name] = az.GetChoicesForName(name)
-		newObject.AttributeValues = append(
-			newObject.AttributeValues,
-			azure.AttributeValue{
-				AttributeName: name,
-			},
-		)
-	}
-	return newObject
-}
-
-func newPTCTemplate(template modelFields) azure.IServerObjectStruct {
-	newObject := azure.IServerObjectStruct{
-		Name:     "",
-		ObjectId: "",
-		AttributeValues: []azure.AttributeValue{
-			{StringValue: "", AttributeName: "Owner"},
-		},
-		ObjectType: struct {
-			Name string `json:"Name"`
-			Id   string `json:"ObjectTypeId"`
-		}{Name: "Physical Technology Component"},
-	}
-	for name := range template.selectValues {
-		azure.ValidChoices[name] = az.GetChoicesForName(name)
-		newObject.AttributeValues = append(
-			newObject.AttributeValues,
-			azure.AttributeValue{
-				AttributeName: name,
-			},
-		)
-	}
-	for name := range template.radioValues {
-		azure.ValidChoices[name] = az.GetChoicesForName(name)
-		newObject.AttributeValues = append(
-			newObject.AttributeValues,
-			azure.AttributeValue{
-				AttributeName: name,
-			},
-		)
-	}
-	for name := range template.checkValues {
-		azure.ValidChoices[name] = az.GetChoicesForName(name)
-		newObject.AttributeValues = append(
-			newObject.AttributeValues,
-			azure.AttributeValue{
-				AttributeName: name,
-			},
-		)
-	}
-	return newObject
-}
 func tidyUp() {
 	fmt.Println("Exited")
 }
@@ -312,6 +281,20 @@ func UpdateMessage(newMessage string) {
 	messages.Set(newMessage)
 }
 
+func objectTypeIcon(objectType string) fyne.Resource {
+	switch objectType {
+	case "Physical Technology Component":
+		return resourcePtcPng
+	case "Physical Application Component":
+		return resourcePacPng
+	case "Logical Application Component":
+		return resourceLacPng
+	case "Physical Data Component":
+		return theme.StorageIcon()
+	}
+	return theme.ComputerIcon()
+}
+
 func ListAndSelectAThing(things []azure.FindStruct, thenWindow *fyne.Window) {
 	display := widget.NewList(
 		func() int { return len(things) },
@@ -323,28 +306,17 @@ func ListAndSelectAThing(things []azure.FindStruct, thenWindow *fyne.Window) {
 		},
 		func(id int, item fyne.CanvasObject) {
 			me := item.(*fyne.Container).Objects[0].(*widget.Button)
-			switch things[id].Type.Name {
-			case "Physical Technology Component":
-				me.SetIcon(resourcePtcPng)
-				me.SetText("PTC")
-			case "Physical Application Component":
-				me.SetIcon(resourcePacPng)
-				me.SetText("PAC")
-			case "Logical Application Component":
-				me.SetIcon(resourceLacPng)
-				me.SetText("LAC")
-			default:
-				// Do nothing
+			me.SetIcon(objectTypeIcon(things[id].Type.Name))
+			me.SetText("")
+			if hasEditForm(things[id].Type.Name) {
+				me.SetText(formFor(things[id].Type.Name).Code)
 			}
 			me.OnTapped = func() {
-				if map[string]bool{
-					"Physical Application Component": true,
-					"Physical Technology Component":  true,
-				}[things[id].Type.Name] {
+				if hasEditForm(things[id].Type.Name) {
 					UpdateMessage("Loading")
 					createEditWindow(
 						fmt.Sprintf("Details for %s", things[id].Name),
-						az.GetImportantFields(things[id].ObjectId, me.Text),
+						az.GetImportantFields(things[id].ObjectId, formFor(things[id].Type.Name).Code),
 						az.FindRelations(things[id].ObjectId),
 					)
 					UpdateMessage("Ready")
@@ -377,6 +349,10 @@ type modelFields struct {
 	checkValues  map[string]*widget.CheckGroup
 	dateValues   map[string]*widget.Entry
 	sections     map[int]sectionStruct
+	// Shown under a field when it won't save
+	errorLabels map[string]*widget.Label
+	// The form the fields were made from
+	form formDefinition
 }
 
 func ListRelationsToSelect(
@@ -385,29 +361,27 @@ func ListRelationsToSelect(
 	thenWindow *fyne.Window,
 ) {
 
-	var allFields modelFields
-	switch basics.ObjectType.Name {
-	case "Physical Application Component":
-		allFields = PacFields()
-	case "Physical Technology Component":
-		allFields = PtcFields()
-	default:
-		allFields = GenFields()
+	form := formFor(basics.ObjectType.Name)
+	allFields := form.modelFields()
+	for _, x := range form.fields() {
+		if x.Choices == choicesProductManagers && allFields.selectValues[x.Attribute] != nil {
+			allFields.selectValues[x.Attribute].Options = settingsProductManagers()
+		}
 	}
-	allFields.stringValues["Description"].Wrapping = fyne.TextWrapWord
-	allFields.stringValues["Description"].SetMinRowsVisible(5)
-	json.Unmarshal([]byte(myApp.Preferences().StringWithFallback("ProductManagers", "[]")), &allFields.selectValues["Owner"].Options)
-	allFields.stringValues["Title"].SetText(basics.Name)
-	selectedRelations := map[string]azure.RelationStruct{}
-	for i := range allFields.dateValues {
-		allFields.dateValues[i].Validator = dateValidator
+	if x, ok := allFields.stringValues["Title"]; ok {
+		x.SetText(basics.Name)
 	}
+	selectedRelations := map[string]azure.RelationStruct{}
+	allFields.errorLabels = map[string]*widget.Label{}
 	isString := func(str string) bool { _, x := allFields.stringValues[str]; return x }
 	isSelect := func(str string) bool { _, x := allFields.selectValues[str]; return x }
 	isRadio := func(str string) bool { _, x := allFields.radioValues[str]; return x }
 	isCheck := func(str string) bool { _, x := allFields.checkValues[str]; return x }
 	isDate := func(str string) bool { _, x := allFields.dateValues[str]; return x }
 	for _, x := range basics.AttributeValues {
+		x.AttributeName = form.attributeName(x.AttributeName)
+		field, _ := form.field(x.AttributeName)
+		loadChoices := field.Choices == "" || field.Choices == choicesIServer
 		switch {
 		case isString(x.AttributeName):
 			allFields.stringValues[x.AttributeName].SetText(x.StringValue)
@@ -415,7 +389,7 @@ func ListRelationsToSelect(
 			if x.AttributeName == "Build" {
 				x.StringValue = strings.Split(x.StringValue, " ")[0]
 			}
-			if x.AttributeName != "Owner" && x.AttributeName != "GU::Managed outside of DS" {
+			if loadChoices {
 				azure.ValidChoices[x.AttributeName] = az.GetChoicesForName(x.AttributeName)
 				keys := getMapStringKeys(azure.ValidChoices[x.AttributeName])
 				sort.Strings(keys)
@@ -426,23 +400,27 @@ func ListRelationsToSelect(
 			}
 			allFields.selectValues[x.AttributeName].Selected = x.StringValue
 		case isRadio(x.AttributeName):
-			azure.ValidChoices[x.AttributeName] = az.GetChoicesForName(x.AttributeName)
-			keys := getMapStringKeys(azure.ValidChoices[x.AttributeName])
-			sort.Strings(keys)
-			allFields.radioValues[x.AttributeName] = widget.NewRadioGroup(
-				keys,
-				func(bob string) {},
-			)
+			if loadChoices {
+				azure.ValidChoices[x.AttributeName] = az.GetChoicesForName(x.AttributeName)
+				keys := getMapStringKeys(azure.ValidChoices[x.AttributeName])
+				sort.Strings(keys)
+				allFields.radioValues[x.AttributeName] = widget.NewRadioGroup(
+					keys,
+					func(bob string) {},
+				)
+			}
 			allFields.radioValues[x.AttributeName].Selected = x.StringValue
 		case isCheck(x.AttributeName):
-			azure.ValidChoices[x.AttributeName] = az.GetChoicesForName(x.AttributeName)
-			keys := getMapStringKeys(azure.ValidChoices[x.AttributeName])
-			sort.Strings(keys)
-			allFields.checkValues[x.AttributeName] = widget.NewCheckGroup(
-				keys,
-				func(bob []string) {},
-			)
-			allFields.checkValues[x.AttributeName].Horizontal = true
+			if loadChoices {
+				azure.ValidChoices[x.AttributeName] = az.GetChoicesForName(x.AttributeName)
+				keys := getMapStringKeys(azure.ValidChoices[x.AttributeName])
+				sort.Strings(keys)
+				allFields.checkValues[x.AttributeName] = widget.NewCheckGroup(
+					keys,
+					func(bob []string) {},
+				)
+				allFields.checkValues[x.AttributeName].Horizontal = true
+			}
 			allFields.checkValues[x.AttributeName].Selected = []string{}
 			for _, elem := range strings.Split(x.StringValue, ",") {
 				allFields.checkValues[x.AttributeName].Selected = append(
@@ -457,6 +435,11 @@ func ListRelationsToSelect(
 			fyne.LogError(message, errors.New(message))
 		}
 	}
+	// As loaded, so only what's changed is saved
+	original := map[string]editValue{}
+	if basics.ObjectId != "" {
+		original = allFields.editValues()
+	}
 	knownKids := map[widget.TreeNodeID][]widget.TreeNodeID{}
 	knownBits := map[widget.TreeNodeID]azure.RelationStruct{}
 	for _, x := range things {
@@ -469,66 +452,112 @@ func ListRelationsToSelect(
 		knownKids,
 		knownBits,
 		thenWindow)
+	tabs := makeEditPage(allFields, relationshipWindow, thenWindow)
+	saveEdits := func(changes []editChange) {
+		stringValuesAsString, selectValuesAsString, dateValuesAsString := editChangeValues(changes)
+		success, message, id := az.SaveObjectFields(
+			basics.ObjectId,
+			allFields.stringValues["Title"].Text,
+			basics.ObjectType.Name,
+			stringValuesAsString,
+			selectValuesAsString,
+			dateValuesAsString,
+		)
+		title := "Save failed"
+		if id != "" {
+			basics.ObjectId = id
+		}
+		if success {
+			title = "Save Succesful"
+			original = allFields.editValues()
+			basics.Name = allFields.stringValues["Title"].Text
+			if modified, _, err := az.GetLastModified(basics.ObjectId); err == nil {
+				basics.LastModifiedDate = modified
+			}
+		}
+		dialog.ShowInformation(title, message, *thenWindow)
+	}
 	display := container.NewBorder(
 		widget.NewToolbar(
 			widget.NewToolbarAction(
 				resourceViewIserver2Png,
 				func() {
-					openbrowser(fmt.Sprintf("https://griffith.iserver365.com/object/%s/details", basics.ObjectId))
+					openbrowser(fmt.Sprintf(iServerObjectURL, basics.ObjectId))
 				},
 			),
 			widget.NewToolbarAction(
 				theme.DocumentSaveIcon(),
 				func() {
-					var d dialog.Dialog
-					d = dialog.NewConfirm("Save", "Are you sure you want to commit these changes?", func(ok bool) {
-						if ok {
-							stringValuesAsString := map[string]string{}
-							selectValuesAsString := map[string]string{}
-							dateValuesAsString := map[string]string{}
-							for i, x := range allFields.stringValues {
-								stringValuesAsString[i] = x.Text
-							}
-							for i, x := range allFields.selectValues {
-								if x.Selected != "" {
-									selectValuesAsString[i] = x.Selected
-								}
+					labels, _ := allFields.fieldLabels()
+					problems := validateEditFields(basics.ObjectType.Name, allFields.editValues(), labels)
+					if showEditProblems(allFields, tabs, problems) > 0 {
+						dialog.ShowError(fmt.Errorf("fix these before saving:\n%s", editProblemSummary(problems, labels)), *thenWindow)
+						return
+					}
+					changes := diffEditValues(original, allFields.editValues(), labels)
+					if len(changes) == 0 {
+						dialog.ShowInformation("Save", "Nothing has changed", *thenWindow)
+						return
+					}
+					mine := allFields.editValues()
+					go func() {
+						// Someone else may have saved it since it was loaded
+						if basics.ObjectId != "" && basics.LastModifiedDate != "" {
+							modified, who, err := az.GetLastModified(basics.ObjectId)
+							if err != nil {
+								dialog.ShowError(fmt.Errorf("could not check whether %s has changed: %w", basics.Name, err), *thenWindow)
+								return
 							}
-							for i, x := range allFields.radioValues {
-								if x.Selected != "" {
-									stringValuesAsString[i] = x.Selected
+							if modified != basics.LastModifiedDate {
+								names := []string{}
+								for name := range mine {
+									names = append(names, name)
 								}
-							}
-							for i, x := range allFields.checkValues {
-								if len(x.Selected) > 0 {
-									selectValuesAsString[i] = strings.Join(x.Selected, ",")
+								current, err := az.GetObjectAttributes(basics.ObjectId, names)
+								if err != nil {
+									dialog.ShowError(err, *thenWindow)
+									return
 								}
+								theirs := allFields.editValuesFrom(current)
+								rows := threeWayRows(original, theirs, mine, labels)
+								showEditMergeWindow(basics.Name, who, rows, func(keepMine map[string]bool) {
+									merged := mergeEditValues(mine, rows, keepMine)
+									allFields.setEditValues(merged)
+									original = theirs
+									basics.LastModifiedDate = modified
+									changes := diffEditValues(theirs, merged, labels)
+									if len(changes) == 0 {
+										dialog.ShowInformation("Save", "iServer already has everything you changed", *thenWindow)
+										return
+									}
+									saveEdits(changes)
+								})
+								return
 							}
-							for i, x := range allFields.dateValues {
-								dateValuesAsString[i] = x.Text
-							}
-							title := "Save Succesful"
-							_, message, id := az.SaveObjectFields(
-								basics.ObjectId,
-								basics.ObjectType.Name,
-								stringValuesAsString,
-								selectValuesAsString,
-								dateValuesAsString,
-							)
-							basics.ObjectId = id
-							d.Hide()
-							d2 := dialog.NewInformation(title, message, *thenWindow)
-							d2.Show()
 						}
-					}, *thenWindow)
-					d.Show()
+						dialog.ShowConfirm("Save", "Are you sure you want to commit these changes?\n\n"+editChangeSummary(changes), func(ok bool) {
+							if ok {
+								saveEdits(changes)
+							}
+						}, *thenWindow)
+					}()
+				},
+			),
+			widget.NewToolbarAction(
+				theme.ContentCopyIcon(),
+				func() {
+					if basics.ObjectId == "" {
+						dialog.ShowInformation("Duplicate", "Save this before duplicating it", *thenWindow)
+						return
+					}
+					showDuplicateWindow(basics, allFields, things)
 				},
 			),
 		),
 		nil,
 		nil,
 		nil,
-		container.NewVScroll(makeEditPage(allFields, relationshipWindow, thenWindow)))
+		container.NewVScroll(tabs))
 	(*thenWindow).SetContent(makeLookupWindow(display))
 }
 
@@ -543,18 +572,23 @@ func makeEditPage(allFields modelFields, relationshipWindow *fyne.Container, thi
 			for j := 0; j < len(row); j++ {
 				fld := row[j]
 				label := widget.NewLabelWithStyle(fld.label, fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+				problem := newErrorLabel()
+				if allFields.errorLabels != nil {
+					allFields.errorLabels[fld.valuesIndex] = problem
+				}
 				switch fld.fieldindex {
 				case "string":
-					rowform.Objects = append(rowform.Objects, container.NewBorder(label, nil, nil, nil, allFields.stringValues[fld.valuesIndex]))
+					rowform.Objects = append(rowform.Objects, container.NewBorder(label, problem, nil, nil, allFields.stringValues[fld.valuesIndex]))
 				case "select":
-					rowform.Objects = append(rowform.Objects, container.NewBorder(label, nil, nil, nil, allFields.selectValues[fld.valuesIndex]))
+					rowform.Objects = append(rowform.Objects, container.NewBorder(label, problem, nil, nil, allFields.selectValues[fld.valuesIndex]))
 				case "radio":
-					rowform.Objects = append(rowform.Objects, container.NewBorder(label, nil, nil, nil, allFields.radioValues[fld.valuesIndex]))
+					rowform.Objects = append(rowform.Objects, container.NewBorder(label, problem, nil, nil, allFields.radioValues[fld.valuesIndex]))
 				case "check":
-					rowform.Objects = append(rowform.Objects, container.NewBorder(label, nil, nil, nil, allFields.checkValues[fld.valuesIndex]))
+					rowform.Objects = append(rowform.Objects, container.NewBorder(label, problem, nil, nil, allFields.checkValues[fld.valuesIndex]))
 				case "date":
 					allFields.dateValues[fld.valuesIndex] = mywidge.CalendarEntry(allFields.dateValues[fld.valuesIndex].Text, *thisWindow)
-					rowform.Objects = append(rowform.Objects, container.NewBorder(label, nil, nil, nil, allFields.dateValues[fld.valuesIndex]))
+					allFields.dateValues[fld.valuesIndex].Validator = dateValidator
+					rowform.Objects = append(rowform.Objects, container.NewBorder(label, problem, nil, nil, allFields.dateValues[fld.valuesIndex]))
 				default:
 					rowform.Objects = append(rowform.Objects, container.NewBorder(label, nil, nil, nil, widget.NewLabel("Unknown "+fld.fieldindex)))
 				}
@@ -628,16 +662,7 @@ func ShowDomainTree(things map[string][]azure.IServerObjectStruct, thenWindow fy
 
 					lookupWindow.SetContent(makeLookupWindow(widget.NewLabel("Loading...")))
 					windows[windowTitle] = lookupWindow
-					objectType := "GEN"
-					switch meps[2] {
-					case "Physical Application Component":
-						objectType = "PAC"
-					case "Physical Technology Component":
-						objectType = "PTC"
-					default:
-						objectType = "GEN"
-					}
-					az.FindRelationsThen(meps[1], objectType, ListRelationsToSelect, &lookupWindow)
+					az.FindRelationsThen(meps[1], formFor(meps[2]).Code, ListRelationsToSelect, &lookupWindow)
 					UpdateMessage("Ready")
 				}
 			}
@@ -682,20 +707,14 @@ func ShowManagersList(list map[string][]string, window *fyne.Window) {
 			widget.NewFormItem("Change PM", widget.NewSelect(pms, func(s string) {
 				replaceThing = s
 			})),
-			widget.NewFormItem("", widget.NewButton("Update", func() {
-
-				dialog.ShowConfirm(
-					"Changing PM",
-					fmt.Sprintf("Change the project managers %s to %s", selectedThing, replaceThing),
-					func(ok bool) {
-						if ok {
-							// find all PAC/PTC that have the project manager selectedThing
-							// Replace the project manager in each with replaceThing
-						}
-					},
-					windows[windowTitle],
-				)
-			})),
+			widget.NewFormItem("", container.NewHBox(
+				widget.NewButton("Update", func() {
+					previewPMChange(selectedThing, replaceThing, windows[windowTitle])
+				}),
+				widget.NewButton("Undo a change", func() {
+					showPMUndoDialog(windows[windowTitle])
+				}),
+			)),
 		),
 
 			nil,
@@ -764,28 +783,32 @@ func nameToToken(alreadyDrawn *map[string]string, name string) string {
 	return attempt
 }
 
+// TOGAF tag used in togaf-full.puml for each iServer object type
+var togafTags = map[string]string{
+	"actor":                          "act",
+	"application service":            "aps",
+	"business service":               "bus",
+	"capability":                     "cap",
+	"constraint":                     "cnt",
+	"data entity":                    "dte",
+	"interface":                      "int",
+	"location":                       "loc",
+	"logical application component":  "lac",
+	"organization unit":              "org",
+	"physical application component": "pac",
+	"physical data component":        "pdc",
+	"physical technology component":  "ptc",
+	"physical technology group":      "ptg",
+	"principle":                      "prn",
+	"process":                        "pro",
+	"product":                        "prd",
+	"requirement":                    "req",
+	"risk":                           "rsk",
+	"role":                           "rol",
+	"technology service":             "tcs",
+}
+
 func drawObject(object objectStruct) string {
-	defaultObjects := map[string]string{
-		"actor":                          "act",
-		"application service":            "aps",
-		"business service":               "bus",
-		"capability":                     "cap",
-		"constraint":                     "cnt",
-		"data entity":                    "dte",
-		"interface":                      "int",
-		"organization unit":              "org",
-		"physical application component": "pac",
-		"physical data component":        "pdc",
-		"physical technology component":  "ptc",
-		"physical technology group":      "ptg",
-		"principle":                      "prn",
-		"process":                        "pro",
-		"product":                        "prd",
-		"requirement":                    "req",
-		"risk":                           "rsk",
-		"role":                           "rol",
-		"technology service":             "tcs",
-	}
 	fmt.Printf("Mapping %s\n", object.otype)
 	switch strings.ToLower(object.otype) {
 	case "location":
@@ -811,11 +834,24 @@ func drawObject(object objectStruct) string {
 			children.String(),
 		)
 	default:
+		if len(object.children) > 0 {
+			children := strings.Builder{}
+			for _, x := range object.children {
+				children.WriteString(drawObject(x))
+			}
+			return fmt.Sprintf(
+				"System_Boundary(%s,\"%s\",$tags=\"%v\") {\n%s}\n",
+				object.alias,
+				object.name,
+				togafTags[strings.ToLower(object.otype)],
+				children.String(),
+			)
+		}
 		return fmt.Sprintf(
 			"System_Boundary(%s,\"%s\",$tags=\"%v\")\n",
 			object.alias,
 			object.name,
-			defaultObjects[strings.ToLower(object.otype)],
+			togafTags[strings.ToLower(object.otype)],
 		)
 	}
 }
@@ -830,9 +866,10 @@ func addToObjectStruct(allObjects *map[string]objectStruct, alias, name, ptype s
 	}
 }
 
-var PlantUMLStart = "@startuml Solution Context\n!include https://raw.githubusercontent.com/colinmo/iserver-diagram/main/togaf/togaf-full.puml\n"
 var PlantUMLEnd = "@enduml"
 
+var iServerObjectURL = "https://griffith.iserver365.com/object/%s/details"
+
 /* Let people press enter to submit a search */
 type enterEntry struct {
 	widget.Entry
@@ -874,205 +911,6 @@ func dateValidator(str string) error {
 	return not
 }
 
-/** Model specific layouts **/
-func PtcFields() modelFields {
-	return modelFields{
-		stringValues: map[string]*widget.Entry{
-			"Title":                            widget.NewEntry(),
-			"Description":                      widget.NewMultiLineEntry(),
-			"GU::Information System Custodian": widget.NewEntry(),
-			"Supplier":                         widget.NewEntry(),
-			"Department":                       widget.NewEntry(),
-		},
-		selectValues: map[string]*widget.Select{
-			"Owner":      widget.NewSelect([]string{}, func(bob string) {}),
-			"GU::Domain": widget.NewSelect([]string{}, func(bob string) {}),
-			"GU::Information Security Classification": widget.NewSelect([]string{}, func(bob string) {}),
-			"GU::Solution Classification":             widget.NewSelect([]string{}, func(bob string) {}),
-			"GU::Object Visibility":                   widget.NewSelect([]string{}, func(bob string) {}),
-			"Lifecycle Status":                        widget.NewSelect([]string{}, func(bob string) {}),
-			"Internal Recommendation":                 widget.NewSelect([]string{}, func(bob string) {}),
-			"Operational Importance":                  widget.NewSelect([]string{}, func(bob string) {}),
-		},
-		radioValues: map[string]*widget.RadioGroup{},
-		checkValues: map[string]*widget.CheckGroup{},
-		dateValues: map[string]*widget.Entry{
-			"Internal: In Development From": widget.NewEntry(),
-			"Internal: Live date":           widget.NewEntry(),
-			"Internal: Phase Out From":      widget.NewEntry(),
-			"Internal: Retirement date":     widget.NewEntry()},
-		sections: map[int]sectionStruct{
-			0: {
-				title: "Basic",
-				fields: map[int]map[int]fieldsStruct{
-					0: {0: {"Name", "string", "Title"}},
-					1: {0: {"Description", "string", "Description"}},
-					2: {0: {"Domain", "select", "GU::Domain"}},
-				},
-			},
-			1: {
-				title: "Roles",
-				fields: map[int]map[int]fieldsStruct{
-					1: {0: {"Owner (Product Manager)", "select", "Owner"}},
-					2: {0: {"Custodian", "string", "GU::Information System Custodian"}},
-					3: {0: {"Supplier", "string", "Supplier"}},
-					4: {0: {"Department (Business Owner)", "string", "Department"}},
-				},
-			},
-			2: {
-				title: "Meta",
-				fields: map[int]map[int]fieldsStruct{
-					0: {0: {"Information Security classification", "select", "GU::Information Security Classification"}},
-					1: {0: {"Solution classification", "select", "GU::Solution Classification"}},
-					2: {0: {"Visible in applist", "select", "GU::Object Visibility"}},
-					4: {0: {"Internal recommendation", "select", "Internal Recommendation"}},
-					5: {0: {"Operational importance", "select", "Operational Importance"}},
-				},
-			},
-			3: {
-				title: "Dates",
-				fields: map[int]map[int]fieldsStruct{
-					0: {0: {"In development", "date", "Internal: In Development From"}},
-					1: {0: {"Live", "date", "Internal: Live date"}},
-					2: {0: {"Phasing out", "date", "Internal: Phase Out From"}},
-					3: {0: {"Retirement", "date", "Internal: Retirement date"}},
-					4: {0: {"Lifecycle Status", "select", "Lifecycle Status"}},
-				},
-			},
-		},
-	}
-}
-
-func PacFields() modelFields {
-	return modelFields{
-		stringValues: map[string]*widget.Entry{
-			"Title":                          widget.NewEntry(),
-			"Description":                    widget.NewMultiLineEntry(),
-			"Alias":                          widget.NewEntry(),
-			"Links":                          widget.NewMultiLineEntry(),
-			"Vendor":                         widget.NewEntry(),
-			"Supplier":                       widget.NewEntry(),
-			"Department":                     widget.NewEntry(),
-			"Approved Usage":                 widget.NewMultiLineEntry(),
-			"Serviceability characteristics": widget.NewEntry(),
-			"Conditions & Restrictions":      widget.NewMultiLineEntry(),
-			"Update Schedule":                widget.NewEntry(),
-			"Vendor Release Details":         widget.NewEntry(),
-		},
-		selectValues: map[string]*widget.Select{
-			"GU::Information Security Classification": widget.NewSelect([]string{}, func(bob string) {}),
-			"Application Type":                        widget.NewSelect([]string{}, func(bob string) {}),
-			"Operational Importance":                  widget.NewSelect([]string{}, func(bob string) {}),
-			"Deployment Method":                       widget.NewSelect([]string{}, func(bob string) {}),
-			"Build":                                   widget.NewSelect([]string{}, func(bob string) {}),
-			"Owner":                                   widget.NewSelect([]string{}, func(bob string) {}),
-			"GU::Domain":                              widget.NewSelect([]string{}, func(bob string) {}),
-			"GU::Managed outside of DS":               widget.NewSelect([]string{"True", "False"}, func(bob string) {}),
-			"GU::Solution Classification":             widget.NewSelect([]string{}, func(bob string) {}),
-			"GU::Object Visibility":                   widget.NewSelect([]string{}, func(bob string) {}),
-			"Internal Recommendation":                 widget.NewSelect([]string{}, func(bob string) {}),
-			"Standards Class":                         widget.NewSelect([]string{}, func(bob string) {}),
-			"Lifecycle Status":                        widget.NewSelect([]string{}, func(bob string) {}),
-		},
-		radioValues: map[string]*widget.RadioGroup{},
-		checkValues: map[string]*widget.CheckGroup{
-			"Categories": widget.NewCheckGroup([]string{}, func(bob []string) {}),
-		},
-		dateValues: map[string]*widget.Entry{
-			"Internal: In Development From": widget.NewEntry(),
-			"Internal: Live date":           widget.NewEntry(),
-			"Internal: Phase Out From":      widget.NewEntry(),
-			"Internal: Retirement date":     widget.NewEntry(),
-			"Date of Last Release":          widget.NewEntry(),
-			"Date of Next Release":          widget.NewEntry(),
-			"Vendor: Contained From":        widget.NewEntry(),
-			"Vendor: Out of Support":        widget.NewEntry(),
-			"Standard Creation Date":        widget.NewEntry(),
-			"Last Standard Review Date":     widget.NewEntry(),
-			"Next Standard Review Date":     widget.NewEntry(),
-			"Standard Retire Date":          widget.NewEntry(),
-		},
-		sections: map[int]sectionStruct{
-			0: {
-				title: "Key attributes",
-				fields: map[int]map[int]fieldsStruct{
-					0: {0: {"Name", "string", "Title"}},
-					1: {0: {"Description", "string", "Description"}},
-					2: {0: {"Domain", "select", "GU::Domain"}},
-					3: {0: {"Alias", "string", "Alias"}},
-					4: {0: {"Links", "string", "Links"}},
-					5: {0: {"Categories", "check", "Categories"}},
-					6: {0: {"Department (Requestor)", "string", "Department"},
-						1: {"Owner (DS Area)", "select", "Owner"}},
-					7: {0: {"Solution classification", "select", "GU::Solution Classification"},
-						1: {"Information security classification", "select", "GU::Information Security Classification"},
-						2: {"Visibility", "select", "GU::Object Visibility"}},
-					8: {0: {"Vendor", "string", "Vendor"},
-						1: {"Supplier", "string", "Supplier"}},
-				},
-			},
-			1: {
-				title: "Lifecycle & Roadmap",
-				fields: map[int]map[int]fieldsStruct{
-					0: {0: {"Lifecycle Status", "select", "Lifecycle Status"}},
-					1: {0: {"Internal recommendation", "select", "Internal Recommendation"}},
-					2: {0: {"Date of Last Release", "date", "Date of Last Release"},
-						1: {"Date of Next Release", "date", "Date of Next Release"}},
-					3: {0: {"In development", "date", "Internal: In Development From"},
-						1: {"Live", "date", "Internal: Live date"},
-						2: {"Phasing out", "date", "Internal: Phase Out From"},
-						3: {"Retirement", "date", "Internal: Retirement date"}},
-					4: {0: {"Vendor Contained From", "date", "Vendor: Contained From"},
-						1: {"Vendor Out of Support", "date", "Vendor: Out of Support"}},
-					5: {0: {"Update Schedule", "string", "Update Schedule"},
-						1: {"Vendor Release Details", "string", "Vendor Release Details"}},
-					6: {0: {"Managed outside of DS", "select", "GU::Managed outside of DS"}},
-					7: {0: {"Serviceability characteristics", "string", "Serviceability characteristics"}},
-				},
-			},
-			2: {
-				title: "Standards & Usage",
-				fields: map[int]map[int]fieldsStruct{
-					0: {0: {"Standards Class", "select", "Standards Class"}},
-					1: {0: {"Standard Creation Date", "date", "Standard Creation Date"},
-						1: {"Last Standard Review Date", "date", "Last Standard Review Date"},
-						2: {"Next Standard Review Date", "date", "Next Standard Review Date"},
-						3: {"Standard Retire Date", "date", "Standard Retire Date"}},
-					2: {0: {"Approved Usage", "string", "Approved Usage"}},
-					3: {0: {"Conditions & Restrictions", "string", "Conditions & Restrictions"}},
-					4: {0: {"Application Type", "select", "Application Type"}},
-					5: {0: {"Operational Importance", "select", "Operational Importance"}},
-					6: {0: {"Deployment Method", "select", "Deployment Method"}},
-					7: {0: {"Build", "select", "Build"}},
-				},
-			},
-		},
-	}
-}
-
-// If there isn't a specific layout, only show name and description
-func GenFields() modelFields {
-	return modelFields{
-		stringValues: map[string]*widget.Entry{
-			"Title":       widget.NewEntry(),
-			"Description": widget.NewMultiLineEntry(),
-		},
-		selectValues: map[string]*widget.Select{},
-		radioValues:  map[string]*widget.RadioGroup{},
-		checkValues:  map[string]*widget.CheckGroup{},
-		dateValues:   map[string]*widget.Entry{},
-		sections: map[int]sectionStruct{
-			0: {
-				title: "Key attributes",
-				fields: map[int]map[int]fieldsStruct{
-					0: {0: {"Name", "string", "Title"}},
-					1: {0: {"Description", "string", "Description"}},
-				},
-			},
-		},
-	}
-}
-
 /** Generic utility functions **/
 func openbrowser(url string) {
 	var err error
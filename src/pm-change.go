@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+	azure "vonexplaino.com/m/v2/vondiagram/azure"
+)
+
+/**
+** Change PM - moves everything one product manager owns to another. The
+** affected objects are previewed first, and each object's old Owner is
+** written to an undo file before anything is changed, so the change can be
+** run backwards from that file.
+**/
+
+var pmUndoSuffix = ".undo.json"
+
+type pmChange struct {
+	ObjectId   string `json:"ObjectId"`
+	Name       string `json:"Name"`
+	ObjectType string `json:"ObjectType"`
+	OldOwner   string `json:"OldOwner"`
+	NewOwner   string `json:"NewOwner"`
+}
+
+func plannedPMChanges(objects []azure.IServerObjectStruct, from, to string) []pmChange {
+	toReturn := []pmChange{}
+	for _, x := range objects {
+		toReturn = append(toReturn, pmChange{
+			ObjectId:   x.ObjectId,
+			Name:       x.Name,
+			ObjectType: x.ObjectType.Name,
+			OldOwner:   from,
+			NewOwner:   to,
+		})
+	}
+	sort.Slice(toReturn, func(i, j int) bool { return toReturn[i].Name < toReturn[j].Name })
+	return toReturn
+}
+
+// Put every object back to the Owner it had
+func reversePMChanges(changes []pmChange) []pmChange {
+	toReturn := []pmChange{}
+	for _, x := range changes {
+		x.OldOwner, x.NewOwner = x.NewOwner, x.OldOwner
+		toReturn = append(toReturn, x)
+	}
+	return toReturn
+}
+
+func pmUndoFileName(savePath, from string, when time.Time) string {
+	return filepath.Join(savePath, fmt.Sprintf(
+		"Change PM %s %s%s",
+		strings.TrimSpace(fileNameNoise.ReplaceAllString(from, "")),
+		when.Format("2006-01-02 150405"),
+		pmUndoSuffix,
+	))
+}
+
+func writePMUndoFile(fileName string, changes []pmChange) error {
+	contents, err := json.MarshalIndent(changes, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, contents, 0644)
+}
+
+func readPMUndoFile(fileName string) ([]pmChange, error) {
+	changes := []pmChange{}
+	contents, err := os.ReadFile(fileName)
+	if err != nil {
+		return changes, err
+	}
+	err = json.Unmarshal(contents, &changes)
+	return changes, err
+}
+
+// Make the changes a new Owner at a time, reporting on each object as it's done
+func runPMChanges(
+	changes []pmChange,
+	replace func(ids []string, owner string, progress func(string, error)) (int, []error),
+	progress func(pmChange, error)) (int, []string) {
+	owners := []string{}
+	ids := map[string][]string{}
+	byId := map[string]pmChange{}
+	for _, x := range changes {
+		if _, ok := ids[x.NewOwner]; !ok {
+			owners = append(owners, x.NewOwner)
+		}
+		ids[x.NewOwner] = append(ids[x.NewOwner], x.ObjectId)
+		byId[x.ObjectId] = x
+	}
+	changed := 0
+	failures := []string{}
+	for _, owner := range owners {
+		done, _ := replace(ids[owner], owner, func(id string, err error) {
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", byId[id].Name, err))
+			}
+			progress(byId[id], err)
+		})
+		changed += done
+	}
+	return changed, failures
+}
+
+// Preview the objects that will change, then change them, keeping an undo file
+func showPMChangeWindow(title, description string, changes []pmChange) {
+	changeWindow := addWindowFor(title, 500, 500)
+
+	statuses := map[string]*widget.Label{}
+	rows := container.NewVBox()
+	for _, x := range changes {
+		statuses[x.ObjectId] = widget.NewLabel("")
+		rows.Add(container.NewBorder(
+			nil,
+			nil,
+			nil,
+			statuses[x.ObjectId],
+			widget.NewLabel(fmt.Sprintf("%s (%s): %s → %s", x.Name, x.ObjectType, x.OldOwner, x.NewOwner)),
+		))
+	}
+	if len(changes) == 0 {
+		rows.Add(widget.NewLabel("Nothing to change"))
+	}
+	progress := widget.NewProgressBar()
+	progress.Max = float64(len(changes))
+
+	var change *widget.Button
+	change = widget.NewButton(fmt.Sprintf("Change %d objects", len(changes)), func() {
+		from := ""
+		if len(changes) > 0 {
+			from = changes[0].OldOwner
+		}
+		undoFile := pmUndoFileName(getSavePath(), from, time.Now())
+		if err := writePMUndoFile(undoFile, changes); err != nil {
+			dialog.ShowError(fmt.Errorf("nothing changed, as the undo file could not be written: %w", err), changeWindow)
+			return
+		}
+		change.Disable()
+		go func() {
+			done := 0
+			changed, failures := runPMChanges(changes, az.ReplaceProductManagers, func(x pmChange, err error) {
+				done++
+				progress.SetValue(float64(done))
+				UpdateMessage(fmt.Sprintf("Changing %d of %d", done, len(changes)))
+				if err != nil {
+					statuses[x.ObjectId].SetText("failed")
+				} else {
+					statuses[x.ObjectId].SetText("done")
+				}
+			})
+			UpdateMessage("Ready")
+			summary := fmt.Sprintf("Changed %d of %d objects.\nUndo file: %s", changed, len(changes), undoFile)
+			if len(failures) == 0 {
+				dialog.ShowInformation(title, summary, changeWindow)
+			} else {
+				dialog.ShowError(fmt.Errorf("%s\nFailed:\n%s", summary, strings.Join(failures, "\n")), changeWindow)
+			}
+		}()
+	})
+	if len(changes) == 0 {
+		change.Disable()
+	}
+
+	changeWindow.SetContent(container.NewBorder(
+		widget.NewLabel(description),
+		container.NewVBox(progress, change),
+		nil,
+		nil,
+		container.NewVScroll(rows),
+	))
+	changeWindow.Show()
+}
+
+// Find what a product manager owns and preview moving it to another
+func previewPMChange(from, to string, thenWindow fyne.Window) {
+	if from == "" || to == "" || from == to {
+		dialog.ShowInformation("Changing PM", "Choose a product manager from the list, and a different one to change to", thenWindow)
+		return
+	}
+	UpdateMessage("Finding what " + from + " owns")
+	go func() {
+		objects, err := az.FindObjectsOwnedBy(from)
+		UpdateMessage("Ready")
+		if err != nil {
+			dialog.ShowError(err, thenWindow)
+			return
+		}
+		showPMChangeWindow("Change PM", fmt.Sprintf("%d objects owned by %s will be given to %s", len(objects), from, to), plannedPMChanges(objects, from, to))
+	}()
+}
+
+// Pick an undo file and preview putting the old Owners back
+func showPMUndoDialog(thenWindow fyne.Window) {
+	d := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, thenWindow)
+			return
+		}
+		if reader == nil {
+			return
+		}
+		reader.Close()
+		changes, err := readPMUndoFile(reader.URI().Path())
+		if err != nil {
+			dialog.ShowError(err, thenWindow)
+			return
+		}
+		showPMChangeWindow("Undo PM change", "Put these objects back to their previous product manager", reversePMChanges(changes))
+	}, thenWindow)
+	d.SetFilter(storage.NewExtensionFileFilter([]string{".json"}))
+	d.Show()
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	azure "vonexplaino.com/m/v2/vondiagram/azure"
+)
+
+func TestPMChange(t *testing.T) {
+	objects := []azure.IServerObjectStruct{{Name: "Zed", ObjectId: "2"}, {Name: "Alpha", ObjectId: "1"}}
+	objects[0].ObjectType.Name = "Physical Application Component"
+	changes := plannedPMChanges(objects, "Pat", "Sam")
+	assert.Equal(t, "Alpha", changes[0].Name)
+	assert.Equal(t, pmChange{ObjectId: "2", Name: "Zed", ObjectType: "Physical Application Component", OldOwner: "Pat", NewOwner: "Sam"}, changes[1])
+
+	fileName := pmUndoFileName(t.TempDir(), "Pat O'Brien", time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC))
+	assert.Contains(t, fileName, "Change PM Pat OBrien 2026-03-04 050607.undo.json")
+	assert.NoError(t, writePMUndoFile(fileName, changes))
+	undo, err := readPMUndoFile(fileName)
+	assert.NoError(t, err)
+	assert.Equal(t, changes, undo)
+
+	undo = reversePMChanges(append(undo, pmChange{ObjectId: "3", Name: "Beta", OldOwner: "Lee", NewOwner: "Sam"}))
+	assert.Equal(t, "Pat", undo[0].NewOwner)
+	calls := map[string][]string{}
+	reported := []string{}
+	changed, failures := runPMChanges(
+		undo,
+		func(ids []string, owner string, progress func(string, error)) (int, []error) {
+			calls[owner] = ids
+			done := 0
+			for _, id := range ids {
+				if id == "2" {
+					progress(id, fmt.Errorf("locked"))
+					continue
+				}
+				progress(id, nil)
+				done++
+			}
+			return done, nil
+		},
+		func(x pmChange, err error) { reported = append(reported, x.Name) },
+	)
+	assert.Equal(t, map[string][]string{"Pat": {"1", "2"}, "Lee": {"3"}}, calls)
+	assert.Equal(t, []string{"Alpha", "Zed", "Beta"}, reported)
+	assert.Equal(t, 2, changed)
+	assert.Equal(t, []string{"Zed: locked"}, failures)
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"math"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	azure "vonexplaino.com/m/v2/vondiagram/azure"
+)
+
+/**
+** Preview of the diagram for the currently selected relationships
+** Uses plantuml.jar when one is configured in Settings, otherwise lays the
+** objects out with a simple force directed graph on a Fyne canvas
+**/
+
+var previewWidth, previewHeight float64 = 600, 400
+
+type previewNode struct {
+	id    string
+	name  string
+	otype string
+	x, y  float64
+}
+
+type previewEdge struct {
+	from, to int
+	label    string
+}
+
+type diagramPreview struct {
+	holder  *fyne.Container
+	lock    sync.Mutex
+	pending *time.Timer
+	version int
+}
+
+func newDiagramPreview() *diagramPreview {
+	return &diagramPreview{
+		holder: container.NewStack(widget.NewLabel("Tick relationships to preview the diagram")),
+	}
+}
+
+// Redraw the preview shortly after the last change, so ticking several boxes only renders once
+func (p *diagramPreview) Update(basics azure.IServerObjectStruct, selectedRelations map[string]azure.RelationStruct, hints diagramDefinition) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.version++
+	me := p.version
+	selected := map[string]azure.RelationStruct{}
+	for i, x := range selectedRelations {
+		selected[i] = x
+	}
+	if p.pending != nil {
+		p.pending.Stop()
+	}
+	p.pending = time.AfterFunc(500*time.Millisecond, func() {
+		drawn := renderPreview(basics, selected, hints)
+		p.lock.Lock()
+		defer p.lock.Unlock()
+		if me != p.version {
+			return
+		}
+		p.holder.Objects = []fyne.CanvasObject{drawn}
+		p.holder.Refresh()
+	})
+}
+
+func renderPreview(basics azure.IServerObjectStruct, selectedRelations map[string]azure.RelationStruct, hints diagramDefinition) fyne.CanvasObject {
+	jar := myApp.Preferences().StringWithFallback("PlantUMLJar", "")
+	if jar != "" {
+		png, err := renderPlantUML(jar, diagramPlantUML(basics, selectedRelations, hints), "png")
+		if err == nil {
+			img := canvas.NewImageFromReader(bytes.NewReader(png), "preview.png")
+			img.FillMode = canvas.ImageFillContain
+			return img
+		}
+		fyne.LogError("PlantUML preview failed, falling back", err)
+	}
+	nodes, edges := previewGraph(basics, selectedRelations)
+	forceLayout(nodes, edges, previewWidth, previewHeight, 300)
+	return drawPreview(nodes, edges)
+}
+
+// Run plantuml.jar over the diagram, returning the png or svg it renders
+func renderPlantUML(jar, puml, format string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("java", "-jar", jar, "-t"+format, "-pipe")
+	cmd.Stdin = strings.NewReader(puml)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%v %s", err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
+
+// Unique objects and the links between them for the selected relationships
+func previewGraph(basics azure.IServerObjectStruct, selectedRelations map[string]azure.RelationStruct) ([]previewNode, []previewEdge) {
+	nodes := []previewNode{{id: basics.ObjectId, name: basics.Name, otype: basics.ObjectType.Name}}
+	index := map[string]int{basics.ObjectId: 0}
+	edges := []previewEdge{}
+	nodeFor := func(id string, object azure.FindStruct) int {
+		if i, ok := index[id]; ok {
+			return i
+		}
+		index[id] = len(nodes)
+		nodes = append(nodes, previewNode{id: id, name: object.Name, otype: object.Type.Name})
+		return index[id]
+	}
+	for _, x := range sortedRelations(selectedRelations) {
+		from := nodeFor(x.LeadObjectId, x.LeadObject)
+		to := nodeFor(x.MemberObjectId, x.MemberObject)
+		edges = append(edges, previewEdge{from: from, to: to, label: x.RelationshipType.LeadToMemberDirection})
+	}
+	return nodes, edges
+}
+
+// Relationships in a stable order so the layout doesn't jump around between redraws
+func sortedRelations(selectedRelations map[string]azure.RelationStruct) []azure.RelationStruct {
+	keys := []string{}
+	for i := range selectedRelations {
+		keys = append(keys, i)
+	}
+	sort.Strings(keys)
+	toReturn := []azure.RelationStruct{}
+	for _, k := range keys {
+		toReturn = append(toReturn, selectedRelations[k])
+	}
+	return toReturn
+}
+
+// Fruchterman-Reingold layout, starting from a circle so the result is repeatable
+func forceLayout(nodes []previewNode, edges []previewEdge, width, height float64, iterations int) {
+	if len(nodes) == 0 {
+		return
+	}
+	margin := 40.0
+	k := math.Sqrt((width - 2*margin) * (height - 2*margin) / float64(len(nodes)))
+	for i := range nodes {
+		angle := 2 * math.Pi * float64(i) / float64(len(nodes))
+		nodes[i].x = width/2 + (width/2-margin)*math.Cos(angle)/2
+		nodes[i].y = height/2 + (height/2-margin)*math.Sin(angle)/2
+	}
+	if len(nodes) == 1 {
+		nodes[0].x, nodes[0].y = width/2, height/2
+		return
+	}
+	temperature := width / 10
+	for iter := 0; iter < iterations; iter++ {
+		dx := make([]float64, len(nodes))
+		dy := make([]float64, len(nodes))
+		for i := range nodes {
+			for j := range nodes {
+				if i == j {
+					continue
+				}
+				xd, yd := nodes[i].x-nodes[j].x, nodes[i].y-nodes[j].y
+				dist := math.Max(math.Hypot(xd, yd), 0.01)
+				force := k * k / dist
+				dx[i] += xd / dist * force
+				dy[i] += yd / dist * force
+			}
+		}
+		for _, e := range edges {
+			xd, yd := nodes[e.from].x-nodes[e.to].x, nodes[e.from].y-nodes[e.to].y
+			dist := math.Max(math.Hypot(xd, yd), 0.01)
+			force := dist * dist / k
+			dx[e.from] -= xd / dist * force
+			dy[e.from] -= yd / dist * force
+			dx[e.to] += xd / dist * force
+			dy[e.to] += yd / dist * force
+		}
+		for i := range nodes {
+			dist := math.Max(math.Hypot(dx[i], dy[i]), 0.01)
+			step := math.Min(dist, temperature)
+			nodes[i].x = math.Min(width-margin, math.Max(margin, nodes[i].x+dx[i]/dist*step))
+			nodes[i].y = math.Min(height-margin, math.Max(margin, nodes[i].y+dy[i]/dist*step))
+		}
+		temperature = math.Max(temperature*(1-1/float64(iterations)), 1)
+	}
+}
+
+func drawPreview(nodes []previewNode, edges []previewEdge) fyne.CanvasObject {
+	objects := []fyne.CanvasObject{}
+	for _, e := range edges {
+		line := canvas.NewLine(theme.ForegroundColor())
+		line.StrokeWidth = 1
+		line.Position1 = fyne.NewPos(float32(nodes[e.from].x), float32(nodes[e.from].y))
+		line.Position2 = fyne.NewPos(float32(nodes[e.to].x), float32(nodes[e.to].y))
+		label := canvas.NewText(e.label, theme.DisabledColor())
+		label.TextSize = 8
+		label.Move(fyne.NewPos(float32(nodes[e.from].x+nodes[e.to].x)/2, float32(nodes[e.from].y+nodes[e.to].y)/2))
+		objects = append(objects, line, label)
+	}
+	iconSize := float32(24)
+	for _, n := range nodes {
+		var icon fyne.CanvasObject
+		if res := previewIcon(n.otype); res != nil {
+			icon = canvas.NewImageFromResource(res)
+		} else {
+			icon = canvas.NewCircle(previewColour(n.otype))
+		}
+		icon.Resize(fyne.NewSize(iconSize, iconSize))
+		icon.Move(fyne.NewPos(float32(n.x)-iconSize/2, float32(n.y)-iconSize/2))
+		label := canvas.NewText(n.name, theme.ForegroundColor())
+		label.TextSize = 10
+		label.Move(fyne.NewPos(float32(n.x)+iconSize/2, float32(n.y)-5))
+		objects = append(objects, icon, label)
+	}
+	drawing := container.NewWithoutLayout(objects...)
+	drawing.Resize(fyne.NewSize(float32(previewWidth), float32(previewHeight)))
+	return container.NewScroll(container.NewGridWrap(fyne.NewSize(float32(previewWidth), float32(previewHeight)), drawing))
+}
+
+func previewIcon(otype string) fyne.Resource {
+	switch otype {
+	case "Physical Application Component":
+		return resourcePacPng
+	case "Physical Technology Component":
+		return resourcePtcPng
+	case "Logical Application Component":
+		return resourceLacPng
+	}
+	return nil
+}
+
+func previewColour(otype string) color.Color {
+	if icon, ok := TogafIcons[togafTags[strings.ToLower(otype)]]; ok {
+		var r, g, b uint8
+		if _, err := fmt.Sscanf(icon.Color, "#%02x%02x%02x", &r, &g, &b); err == nil {
+			return color.NRGBA{R: r, G: g, B: b, A: 255}
+		}
+	}
+	return color.NRGBA{R: 160, G: 160, B: 160, A: 255}
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForceLayout(t *testing.T) {
+	nodes := []previewNode{{id: "1"}, {id: "2"}, {id: "3"}, {id: "4"}}
+	edges := []previewEdge{{from: 0, to: 1}, {from: 0, to: 2}, {from: 0, to: 3}}
+	forceLayout(nodes, edges, 600, 400, 300)
+	seen := map[[2]int]bool{}
+	for _, n := range nodes {
+		assert.GreaterOrEqual(t, n.x, 40.0)
+		assert.LessOrEqual(t, n.x, 560.0)
+		assert.GreaterOrEqual(t, n.y, 40.0)
+		assert.LessOrEqual(t, n.y, 360.0)
+		key := [2]int{int(n.x), int(n.y)}
+		assert.False(t, seen[key], "nodes should not overlap")
+		seen[key] = true
+	}
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	azure "vonexplaino.com/m/v2/vondiagram/azure"
+)
+
+/**
+** Data quality - rules run over a domain's applications, technology and
+** logical components. Each rule names the object types it applies to and
+** returns what's wrong; the issues are shown in the Audit tab, printed by
+** `quality <domain>` and written to the workbook's Data Quality sheet.
+**/
+
+var (
+	severityError   = "Error"
+	severityWarning = "Warning"
+	severityInfo    = "Info"
+)
+
+var severityOrder = map[string]int{severityError: 0, severityWarning: 1, severityInfo: 2}
+
+type qualityContext struct {
+	today time.Time
+	// Product managers from Settings; empty skips the check
+	productManagers map[string]bool
+	// Lifecycle Status of the domain's objects, retired ones included, by id
+	lifecycles map[string]string
+}
+
+type qualityRule struct {
+	Name        string
+	Severity    string
+	Description string
+	// Object types the rule applies to; empty for all
+	ObjectTypes []string
+	Check       func(x auditObject, context qualityContext) []string
+}
+
+type qualityIssue struct {
+	Object  auditObject
+	Rule    qualityRule
+	Message string
+}
+
+// Attributes the rules look at
+var qualityAttributes = []string{
+	"Owner",
+	"Description",
+	"Lifecycle Status",
+	roadmapLive,
+	"Internal: Live Date",
+	roadmapRetirement,
+	"Internal: Retirement Date",
+	roadmapNextReview,
+}
+
+var qualityObjectTypes = []string{"Physical Application Component", "Physical Technology Component", "Logical Application Component"}
+
+// Ignoring case, as iServer isn't consistent with some attribute names
+func (x auditObject) value(name string) string {
+	for i, y := range x.fields {
+		if strings.EqualFold(i, name) && strings.TrimSpace(y) != "" {
+			return strings.TrimSpace(y)
+		}
+	}
+	return ""
+}
+
+func requireAttribute(name string) func(auditObject, qualityContext) []string {
+	return func(x auditObject, _ qualityContext) []string {
+		if x.value(name) == "" {
+			return []string{"No " + name}
+		}
+		return nil
+	}
+}
+
+func requireLink(objectType string) func(auditObject, qualityContext) []string {
+	return func(x auditObject, _ qualityContext) []string {
+		if len(x.related[objectType]) == 0 {
+			return []string{"Not linked to a " + objectType}
+		}
+		return nil
+	}
+}
+
+var qualityRules = []qualityRule{
+	{
+		Name:        "missing-owner",
+		Severity:    severityError,
+		Description: "Every object needs a product manager",
+		Check:       requireAttribute("Owner"),
+	},
+	{
+		Name:        "missing-description",
+		Severity:    severityWarning,
+		Description: "Every object needs a description",
+		Check:       requireAttribute("Description"),
+	},
+	{
+		Name:        "missing-department",
+		Severity:    severityWarning,
+		Description: "Every object needs a business owner",
+		Check:       requireAttribute("Department"),
+	},
+	{
+		Name:        "missing-serviceability",
+		Severity:    severityWarning,
+		Description: "Every object needs its serviceability characteristics",
+		Check:       requireAttribute("Serviceability characteristics"),
+	},
+	{
+		Name:        "unknown-product-manager",
+		Severity:    severityWarning,
+		Description: "The Owner should be one of the product managers in Settings",
+		Check: func(x auditObject, context qualityContext) []string {
+			owner := x.value("Owner")
+			if owner == "" || len(context.productManagers) == 0 || context.productManagers[productManagerKey(owner)] {
+				return nil
+			}
+			return []string{owner + " is not a known product manager"}
+		},
+	},
+	{
+		Name:        "live-without-live-date",
+		Severity:    severityWarning,
+		Description: "Live objects should say when they went live",
+		Check: func(x auditObject, _ qualityContext) []string {
+			if x.value("Lifecycle Status") == "Live" && x.value(roadmapLive) == "" {
+				return []string{"Live but has no Live date"}
+			}
+			return nil
+		},
+	},
+	{
+		Name:        "retired-before-live",
+		Severity:    severityError,
+		Description: "The retirement date can't be before the Live date",
+		Check: func(x auditObject, _ qualityContext) []string {
+			live, isLive := parseIServerDate(x.value(roadmapLive))
+			retired, isRetired := parseIServerDate(x.value(roadmapRetirement))
+			if isLive && isRetired && retired.Before(live) {
+				return []string{fmt.Sprintf("Retires %s, before going live %s", retired.Format("2006-01-02"), live.Format("2006-01-02"))}
+			}
+			return nil
+		},
+	},
+	{
+		Name:        "standard-review-overdue",
+		Severity:    severityWarning,
+		Description: "The Next Standard Review Date has passed",
+		Check: func(x auditObject, context qualityContext) []string {
+			if when, ok := parseIServerDate(x.value(roadmapNextReview)); ok && when.Before(context.today) {
+				return []string{"Standard review was due " + when.Format("2006-01-02")}
+			}
+			return nil
+		},
+	},
+	{
+		Name:        "retired-but-used",
+		Severity:    severityWarning,
+		Description: "Objects in use shouldn't still be related to Retired ones",
+		Check: func(x auditObject, context qualityContext) []string {
+			toReturn := []string{}
+			for _, y := range x.related {
+				for _, z := range y {
+					if context.lifecycles[z.ObjectId] == "Retired" {
+						toReturn = append(toReturn, "Related to Retired "+z.Name)
+					}
+				}
+			}
+			sort.Strings(toReturn)
+			return toReturn
+		},
+	},
+	{
+		Name:        "application-without-capability",
+		Severity:    severityWarning,
+		Description: "Applications should support a Capability",
+		ObjectTypes: []string{"Physical Application Component"},
+		Check:       requireLink("Capability"),
+	},
+	{
+		Name:        "application-without-technology",
+		Severity:    severityInfo,
+		Description: "Applications should run on some technology",
+		ObjectTypes: []string{"Physical Application Component"},
+		Check:       requireLink("Physical Technology Component"),
+	},
+	{
+		Name:        "unused-technology",
+		Severity:    severityInfo,
+		Description: "Technology should be used by an application",
+		ObjectTypes: []string{"Physical Technology Component"},
+		Check:       requireLink("Physical Application Component"),
+	},
+	{
+		Name:        "unrealised-logical",
+		Severity:    severityInfo,
+		Description: "Logical components should be realised by an application",
+		ObjectTypes: []string{"Logical Application Component"},
+		Check:       requireLink("Physical Application Component"),
+	},
+}
+
+// Owners are spelt with both "&" and "and", so treat them the same
+func productManagerKey(name string) string {
+	return strings.ReplaceAll(strings.TrimSpace(name), "&", "and")
+}
+
+// Lifecycles come from every object in the domain, as the rules are only run over those not retired
+func newQualityContext(everything []azure.IServerObjectStruct, productManagers []string, today time.Time) qualityContext {
+	context := qualityContext{today: today, productManagers: map[string]bool{}, lifecycles: map[string]string{}}
+	for _, x := range productManagers {
+		context.productManagers[productManagerKey(x)] = true
+	}
+	for _, x := range everything {
+		for _, y := range x.AttributeValues {
+			if strings.EqualFold(y.AttributeName, "Lifecycle Status") {
+				context.lifecycles[x.ObjectId] = strings.TrimSpace(y.StringValue)
+			}
+		}
+	}
+	return context
+}
+
+// Run every rule over every object it applies to, worst first
+func runQualityRules(rules []qualityRule, objects []auditObject, context qualityContext) []qualityIssue {
+	toReturn := []qualityIssue{}
+	for _, x := range objects {
+		for _, rule := range rules {
+			if len(rule.ObjectTypes) > 0 && !containsString(rule.ObjectTypes, x.ObjectType.Name) {
+				continue
+			}
+			for _, message := range rule.Check(x, context) {
+				toReturn = append(toReturn, qualityIssue{Object: x, Rule: rule, Message: message})
+			}
+		}
+	}
+	sort.SliceStable(toReturn, func(i, j int) bool {
+		if toReturn[i].Rule.Severity != toReturn[j].Rule.Severity {
+			return severityOrder[toReturn[i].Rule.Severity] < severityOrder[toReturn[j].Rule.Severity]
+		}
+		return toReturn[i].Object.Name < toReturn[j].Object.Name
+	})
+	return toReturn
+}
+
+func containsString(haystack []string, needle string) bool {
+	for _, x := range haystack {
+		if x == needle {
+			return true
+		}
+	}
+	return false
+}
+
+func settingsProductManagers() []string {
+	pms := []string{}
+	json.Unmarshal([]byte(myApp.Preferences().StringWithFallback("ProductManagers", "[]")), &pms)
+	return pms
+}
+
+// Check the domain's objects that aren't retired against the rules
+func checkDomainQuality(domain string) ([]auditObject, []azure.RelationStruct, []qualityIssue, error) {
+	objects, relations, err := fetchAuditObjects(domain)
+	if err != nil {
+		return objects, relations, nil, err
+	}
+	everything, err := az.GetDomainObjects(domain, qualityObjectTypes, []string{"Lifecycle Status"}, true)
+	if err != nil {
+		return objects, relations, nil, err
+	}
+	return objects, relations, runQualityRules(qualityRules, objects, newQualityContext(everything, settingsProductManagers(), time.Now())), nil
+}
+
+// Command line `quality <domain>`, printing one issue per line; fails when there are errors
+func runQualityCLI(args []string) int {
+	az.StartAzure()
+	domain := strings.Join(args, " ")
+	_, _, issues, err := checkDomainQuality(domain)
+	if err != nil {
+		fmt.Printf("%s: %v\n", domain, err)
+		return 1
+	}
+	errors := 0
+	for _, x := range issues {
+		fmt.Printf("%s\t%s\t%s\t%s\t%s\n", x.Rule.Severity, x.Rule.Name, x.Object.ObjectType.Name, x.Object.Name, x.Message)
+		if x.Rule.Severity == severityError {
+			errors++
+		}
+	}
+	fmt.Printf("%d issues, %d errors\n", len(issues), errors)
+	if errors > 0 {
+		return 1
+	}
+	return 0
+}
+
+func showQualityDialog(thenWindow fyne.Window) {
+	domain := myApp.Preferences().StringWithFallback("Department", "")
+	if domain == "" {
+		dialog.ShowInformation("Data quality", "Choose a domain in Settings first", thenWindow)
+		return
+	}
+	UpdateMessage("Checking " + domain)
+	go func() {
+		_, _, issues, err := checkDomainQuality(domain)
+		UpdateMessage("Ready")
+		if err != nil {
+			dialog.ShowError(err, thenWindow)
+			return
+		}
+		showQualityWindow(domain, issues)
+	}()
+}
+
+func showQualityWindow(domain string, issues []qualityIssue) {
+	qualityWindow := addWindowFor("Data quality "+domain, 800, 500)
+	shown := []qualityIssue{}
+	list := widget.NewList(
+		func() int { return len(shown) },
+		func() fyne.CanvasObject {
+			return container.NewBorder(nil, nil, widget.NewLabel("Warning"), nil, widget.NewLabel("template"))
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			x := shown[id]
+			row := item.(*fyne.Container)
+			row.Objects[0].(*widget.Label).SetText(fmt.Sprintf("%s: %s", x.Object.Name, x.Message))
+			row.Objects[1].(*widget.Label).SetText(x.Rule.Severity)
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) {
+		openbrowser(fmt.Sprintf(iServerObjectURL, shown[id].Object.ObjectId))
+		list.UnselectAll()
+	}
+	counts := map[string]int{}
+	for _, x := range issues {
+		counts[x.Rule.Severity]++
+	}
+	severities := widget.NewCheckGroup([]string{severityError, severityWarning, severityInfo}, func(chosen []string) {
+		shown = []qualityIssue{}
+		for _, x := range issues {
+			if containsString(chosen, x.Rule.Severity) {
+				shown = append(shown, x)
+			}
+		}
+		list.Refresh()
+	})
+	severities.Horizontal = true
+	severities.SetSelected([]string{severityError, severityWarning, severityInfo})
+	qualityWindow.SetContent(container.NewBorder(
+		container.NewVBox(
+			widget.NewLabel(fmt.Sprintf(
+				"%d errors, %d warnings, %d for information. Click an issue to open the object in iServer.",
+				counts[severityError],
+				counts[severityWarning],
+				counts[severityInfo],
+			)),
+			severities,
+		),
+		nil,
+		nil,
+		nil,
+		list,
+	))
+	qualityWindow.Show()
+}
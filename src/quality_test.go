@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	azure "vonexplaino.com/m/v2/vondiagram/azure"
+)
+
+func TestQualityRules(t *testing.T) {
+	pac := "Physical Application Component"
+	testObject := func(id, lifecycle string, attributes ...string) azure.IServerObjectStruct {
+		x := azure.IServerObjectStruct{Name: id, ObjectId: id}
+		x.ObjectType.Name = pac
+		x.AttributeValues = []azure.AttributeValue{
+			{AttributeName: "Lifecycle Status", StringValue: lifecycle},
+			{AttributeName: "Description", StringValue: "Something"},
+			{AttributeName: "Department", StringValue: "ITS"},
+			{AttributeName: "Serviceability characteristics", StringValue: "Gold"},
+		}
+		for i := 0; i+1 < len(attributes); i += 2 {
+			x.AttributeValues = append(x.AttributeValues, azure.AttributeValue{AttributeName: attributes[i], StringValue: attributes[i+1]})
+		}
+		return x
+	}
+	rels := []azure.RelationStruct{
+		testRelation("r1", "Good", pac, "supports", "Cap", "Capability"),
+		testRelation("r2", "Good", pac, "is hosted on", "Host", "Physical Technology Component"),
+		testRelation("r3", "Old", pac, "uses", "Good", pac),
+	}
+	checked := []azure.IServerObjectStruct{
+		testObject("Good", "Live", "Owner", "Pat", "Internal: Live Date", "2020-01-01T00:00:00Z", "Next Standard Review Date", "2027-01-01T00:00:00Z"),
+		testObject("Soon", "Phasing Out", "Owner", "Sam", "Internal: Live date", "2020-01-01T00:00:00Z", "Internal: Retirement date", "2019-01-01T00:00:00Z"),
+		testObject("Bad", "Live", "Next Standard Review Date", "2024-01-01T00:00:00Z"),
+	}
+	objects, _ := buildAuditObjects(checked, func(id string) []azure.RelationStruct {
+		toReturn := []azure.RelationStruct{}
+		for _, x := range rels {
+			if x.LeadObjectId == id || x.MemberObjectId == id {
+				toReturn = append(toReturn, x)
+			}
+		}
+		return toReturn
+	})
+	// Retired objects aren't checked, but what's related to them is
+	everything := append(checked, testObject("Old", "Retired"))
+	context := newQualityContext(everything, []string{"Pat"}, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	found := []string{}
+	for _, x := range runQualityRules(qualityRules, objects, context) {
+		found = append(found, x.Rule.Severity+" "+x.Object.Name+" "+x.Rule.Name+": "+x.Message)
+	}
+	assert.Equal(t, []string{
+		"Error Bad missing-owner: No Owner",
+		"Error Soon retired-before-live: Retires 2019-01-01, before going live 2020-01-01",
+		"Warning Bad live-without-live-date: Live but has no Live date",
+		"Warning Bad standard-review-overdue: Standard review was due 2024-01-01",
+		"Warning Bad application-without-capability: Not linked to a Capability",
+		"Warning Good retired-but-used: Related to Retired Old",
+		"Warning Soon unknown-product-manager: Sam is not a known product manager",
+		"Warning Soon application-without-capability: Not linked to a Capability",
+		"Info Bad application-without-technology: Not linked to a Physical Technology Component",
+		"Info Soon application-without-technology: Not linked to a Physical Technology Component",
+	}, found)
+}
+
+func TestUnknownProductManager(t *testing.T) {
+	var rule qualityRule
+	for _, x := range qualityRules {
+		if x.Name == "unknown-product-manager" {
+			rule = x
+		}
+	}
+	context := newQualityContext(nil, []string{"Finance and Business", "Learning & Teaching"}, time.Now())
+	owned := func(owner string) auditObject {
+		return auditObject{fields: map[string]string{"Owner": owner}}
+	}
+	assert.Empty(t, rule.Check(owned("Finance & Business"), context))
+	assert.Empty(t, rule.Check(owned("Learning and Teaching"), context))
+	assert.Equal(t, []string{"Research is not a known product manager"}, rule.Check(owned("Research"), context))
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	azure "vonexplaino.com/m/v2/vondiagram/azure"
+	"vonexplaino.com/m/v2/vondiagram/c4puml"
+)
+
+/**
+** Reconcile - reads a hand drawn PlantUML file and checks its elements and
+** Rel lines against iServer, offering to create the missing relationships
+**/
+
+type reconcileRelation struct {
+	relation c4puml.Relationship
+	lead     azure.FindStruct
+	member   azure.FindStruct
+	// Relationships iServer has between the two that aren't the one drawn
+	others []azure.RelationStruct
+}
+
+// An element whose name or alias is on more than one iServer object
+type reconcileAmbiguity struct {
+	element c4puml.Container
+	found   []azure.FindStruct
+}
+
+type reconcileResult struct {
+	matched map[string]azure.FindStruct
+	// Elements with no iServer object of that name or alias
+	missingElements []c4puml.Container
+	// Elements that could be any of several iServer objects, so aren't matched
+	ambiguousElements []reconcileAmbiguity
+	// Rel lines between two known objects that have no relationship of that type in iServer
+	missingRelations []reconcileRelation
+	// Rel lines where at least one end isn't in iServer, so can't be created
+	unresolvedRelations []c4puml.Relationship
+}
+
+// Whether an iServer relationship is the one a Rel line draws: joining the same
+// objects either way round and, when the line is labelled, of that type
+func relationDrawn(x azure.RelationStruct, label, leadId, memberId string) bool {
+	if !(x.LeadObjectId == leadId && x.MemberObjectId == memberId) && !(x.LeadObjectId == memberId && x.MemberObjectId == leadId) {
+		return false
+	}
+	label = strings.TrimSpace(label)
+	return label == "" ||
+		strings.EqualFold(label, x.RelationshipType.Name) ||
+		strings.EqualFold(label, x.RelationshipType.LeadToMemberDirection)
+}
+
+// The type's pair joining the source's object type to the target's, or failing that the target's to the source's
+func relationshipTypePair(x azure.RelationshipTypeStruct, sourceTypeId, targetTypeId string) (string, bool, error) {
+	for _, y := range x.RelationshipTypePairs {
+		if y.LeadObjectTypeId == sourceTypeId && y.MemberObjectTypeId == targetTypeId {
+			return y.RelationshipTypePairId, true, nil
+		}
+	}
+	for _, y := range x.RelationshipTypePairs {
+		if y.LeadObjectTypeId == targetTypeId && y.MemberObjectTypeId == sourceTypeId {
+			return y.RelationshipTypePairId, false, nil
+		}
+	}
+	return "", false, fmt.Errorf(
+		"%s can't be between a %s and a %s",
+		x.Name,
+		azure.ObjectTypesListLookup[sourceTypeId],
+		azure.ObjectTypesListLookup[targetTypeId],
+	)
+}
+
+// Match each element by name then alias, then look for the relationship each Rel line draws
+func reconcileChart(
+	chart c4puml.Chart,
+	find func(string) ([]azure.FindStruct, error),
+	relationsFor func(string) []azure.RelationStruct) (reconcileResult, error) {
+	result := reconcileResult{matched: map[string]azure.FindStruct{}}
+	seen := map[string]bool{}
+	for _, x := range chart.Elements() {
+		if seen[x.Alias] {
+			continue
+		}
+		seen[x.Alias] = true
+		found, err := find(x.Name)
+		if err == nil && len(found) == 0 && x.Alias != x.Name {
+			found, err = find(x.Alias)
+		}
+		if err != nil {
+			return result, fmt.Errorf("could not look up %s: %w", relationEndName(x), err)
+		}
+		switch len(found) {
+		case 0:
+			result.missingElements = append(result.missingElements, x)
+		case 1:
+			result.matched[x.Alias] = found[0]
+		default:
+			result.ambiguousElements = append(result.ambiguousElements, reconcileAmbiguity{element: x, found: found})
+		}
+	}
+	knownRelations := map[string][]azure.RelationStruct{}
+	for _, x := range chart.Relationships {
+		lead, leadOk := result.matched[x.From.Alias]
+		member, memberOk := result.matched[x.To.Alias]
+		if !leadOk || !memberOk {
+			result.unresolvedRelations = append(result.unresolvedRelations, x)
+			continue
+		}
+		if _, fetched := knownRelations[lead.ObjectId]; !fetched {
+			knownRelations[lead.ObjectId] = relationsFor(lead.ObjectId)
+		}
+		exists := false
+		others := []azure.RelationStruct{}
+		for _, y := range knownRelations[lead.ObjectId] {
+			if relationDrawn(y, x.Label, lead.ObjectId, member.ObjectId) {
+				exists = true
+				break
+			}
+			if relationDrawn(y, "", lead.ObjectId, member.ObjectId) {
+				others = append(others, y)
+			}
+		}
+		if !exists {
+			result.missingRelations = append(result.missingRelations, reconcileRelation{relation: x, lead: lead, member: member, others: others})
+		}
+	}
+	return result, nil
+}
+
+// Pick a PlantUML file and show what doesn't line up with iServer
+func showReconcileDialog(thenWindow fyne.Window) {
+	dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, thenWindow)
+			return
+		}
+		if reader == nil {
+			return
+		}
+		defer reader.Close()
+		contents, err := io.ReadAll(reader)
+		if err != nil {
+			dialog.ShowError(err, thenWindow)
+			return
+		}
+		chart := c4puml.Parse(string(contents))
+		fileName := reader.URI().Name()
+		UpdateMessage("Reconciling")
+		go func() {
+			result, err := reconcileChart(chart, az.FindObjectsByName, az.FindRelations)
+			UpdateMessage("Ready")
+			if err != nil {
+				dialog.ShowError(err, thenWindow)
+				return
+			}
+			showReconcileWindow(fileName, result)
+		}()
+	}, thenWindow)
+}
+
+func showReconcileWindow(fileName string, result reconcileResult) {
+	reconcileWindow := addWindowFor("Reconcile "+fileName, 700, 500)
+
+	missingElements := container.NewVBox()
+	for _, x := range result.missingElements {
+		missingElements.Add(widget.NewLabel(fmt.Sprintf("%s (%s)", x.Name, x.TOGAF)))
+	}
+	for _, x := range result.ambiguousElements {
+		candidates := []string{}
+		for _, y := range x.found {
+			candidates = append(candidates, fmt.Sprintf("%s (%s)", y.Name, y.Type.Name))
+		}
+		missingElements.Add(widget.NewLabel(fmt.Sprintf("%s (%s) could be any of: %s", x.element.Name, x.element.TOGAF, strings.Join(candidates, ", "))))
+	}
+	if len(result.missingElements) == 0 && len(result.ambiguousElements) == 0 {
+		missingElements.Add(widget.NewLabel("All elements found"))
+	}
+
+	unresolved := container.NewVBox()
+	for _, x := range result.unresolvedRelations {
+		unresolved.Add(widget.NewLabel(fmt.Sprintf("%s → %s \"%s\"", relationEndName(x.From), relationEndName(x.To), x.Label)))
+	}
+
+	type creatable struct {
+		row   reconcileRelation
+		tick  *widget.Check
+		types *widget.Select
+		found map[string]azure.RelationshipTypeStruct
+	}
+	creatables := []creatable{}
+	typesCache := map[string]map[string]azure.RelationshipTypeStruct{}
+	missingRelations := container.NewVBox()
+	for _, x := range result.missingRelations {
+		leadType := azure.ObjectTypeIdFor(x.lead.Type.Name)
+		memberType := azure.ObjectTypeIdFor(x.member.Type.Name)
+		key := leadType + memberType
+		if _, ok := typesCache[key]; !ok {
+			typesCache[key] = map[string]azure.RelationshipTypeStruct{}
+			for _, y := range az.GetRelationTypesForObjectType(leadType, memberType) {
+				typesCache[key][y.Name] = y
+			}
+		}
+		names := []string{}
+		for i := range typesCache[key] {
+			names = append(names, i)
+		}
+		sort.Strings(names)
+		types := widget.NewSelect(names, func(s string) {})
+		for _, y := range names {
+			if strings.EqualFold(y, x.relation.Label) {
+				types.SetSelected(y)
+			}
+		}
+		if len(names) == 1 {
+			types.SetSelected(names[0])
+		}
+		description := fmt.Sprintf("%s → %s \"%s\"", x.lead.Name, x.member.Name, x.relation.Label)
+		if len(x.others) > 0 {
+			has := []string{}
+			for _, y := range x.others {
+				has = append(has, y.RelationshipType.LeadToMemberDirection)
+			}
+			description += fmt.Sprintf(" (iServer has %s)", strings.Join(has, ", "))
+		}
+		tick := widget.NewCheck(description, func(b bool) {})
+		tick.SetChecked(len(types.Selected) > 0)
+		creatables = append(creatables, creatable{row: x, tick: tick, types: types, found: typesCache[key]})
+		missingRelations.Add(container.NewBorder(nil, nil, nil, types, tick))
+	}
+	if len(result.missingRelations) == 0 {
+		missingRelations.Add(widget.NewLabel("No relationships to create"))
+	}
+
+	reconcileWindow.SetContent(container.NewBorder(
+		widget.NewLabel(fmt.Sprintf(
+			"%d elements matched, %d missing, %d ambiguous; %d relationships missing, %d can't be checked",
+			len(result.matched),
+			len(result.missingElements),
+			len(result.ambiguousElements),
+			len(result.missingRelations),
+			len(result.unresolvedRelations),
+		)),
+		widget.NewButton("Create ticked relationships", func() {
+			errors := []string{}
+			made := 0
+			for _, x := range creatables {
+				if !x.tick.Checked {
+					continue
+				}
+				relType, ok := x.found[x.types.Selected]
+				if !ok {
+					errors = append(errors, fmt.Sprintf("%s: no relationship type chosen", x.tick.Text))
+					continue
+				}
+				pairId, fromLeads, err := relationshipTypePair(relType, azure.ObjectTypeIdFor(x.row.lead.Type.Name), azure.ObjectTypeIdFor(x.row.member.Type.Name))
+				if err != nil {
+					errors = append(errors, fmt.Sprintf("%s: %v", x.tick.Text, err))
+					continue
+				}
+				lead, member := x.row.lead.ObjectId, x.row.member.ObjectId
+				if !fromLeads {
+					lead, member = member, lead
+				}
+				if err := az.CreateRelationship(
+					relType.RelationshipTypeId,
+					pairId,
+					lead,
+					member,
+				); err != nil {
+					errors = append(errors, fmt.Sprintf("%s: %v", x.tick.Text, err))
+					continue
+				}
+				x.tick.SetChecked(false)
+				x.tick.Disable()
+				made++
+			}
+			if len(errors) == 0 {
+				dialog.ShowInformation("Reconcile", fmt.Sprintf("Created %d relationships", made), reconcileWindow)
+			} else {
+				dialog.ShowError(fmt.Errorf("created %d relationships, but:\n%s", made, strings.Join(errors, "\n")), reconcileWindow)
+			}
+		}),
+		nil,
+		nil,
+		container.NewAppTabs(
+			container.NewTabItem("Missing relationships", container.NewVScroll(missingRelations)),
+			container.NewTabItem("Missing elements", container.NewVScroll(missingElements)),
+			container.NewTabItem("Unchecked relationships", container.NewVScroll(unresolved)),
+		),
+	))
+	reconcileWindow.Show()
+}
+
+func relationEndName(x c4puml.Container) string {
+	if len(x.Name) > 0 {
+		return x.Name
+	}
+	return x.Alias
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	azure "vonexplaino.com/m/v2/vondiagram/azure"
+	"vonexplaino.com/m/v2/vondiagram/c4puml"
+)
+
+func TestReconcileChart(t *testing.T) {
+	chart := c4puml.Parse(`@startuml
+System_Boundary(RIMS,"RIMS (endpoint IQ)",$tags="pac")
+System_Boundary(GDH,"Griffith Data Hub",$tags="ptc")
+System_Boundary(CM10,"Content Manager 10",$tags="pac")
+System_Boundary(Nowhere,"Not In iServer",$tags="pac")
+System_Boundary(Twice,"Twice",$tags="pac")
+Rel_R(RIMS,GDH,"")
+Rel_L(RIMS,CM10,"uses")
+Rel_D(RIMS,Nowhere,"")
+Rel_U(RIMS,GDH,"is hosted on")
+Rel_U(RIMS,GDH,"uses")
+Rel_U(RIMS,Twice,"uses")
+@enduml`)
+	objects := map[string][]azure.FindStruct{
+		"RIMS (endpoint IQ)": {{ObjectId: "1", Name: "RIMS (endpoint IQ)"}},
+		"GDH":                {{ObjectId: "2", Name: "Griffith Data Hub"}},
+		"Content Manager 10": {{ObjectId: "3", Name: "Content Manager 10"}},
+		"Twice":              {{ObjectId: "4", Name: "Twice"}, {ObjectId: "5", Name: "Twice"}},
+	}
+	hostedOn := testRelation("r1", "1", "Physical Application Component", "is hosted on", "2", "Physical Technology Component")
+	fetched := []string{}
+	result, err := reconcileChart(
+		chart,
+		func(name string) ([]azure.FindStruct, error) {
+			return objects[name], nil
+		},
+		func(id string) []azure.RelationStruct {
+			fetched = append(fetched, id)
+			return []azure.RelationStruct{hostedOn}
+		},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "2", result.matched["GDH"].ObjectId, "falls back to alias")
+	assert.Len(t, result.missingElements, 1)
+	assert.Equal(t, "Not In iServer", result.missingElements[0].Name)
+	assert.Len(t, result.ambiguousElements, 1)
+	assert.Equal(t, "Twice", result.ambiguousElements[0].element.Name)
+	assert.Len(t, result.ambiguousElements[0].found, 2)
+	assert.Len(t, result.missingRelations, 2)
+	assert.Equal(t, "3", result.missingRelations[0].member.ObjectId)
+	assert.Empty(t, result.missingRelations[0].others)
+	assert.Equal(t, "uses", result.missingRelations[1].relation.Label, "another type between the two doesn't count")
+	assert.Equal(t, []azure.RelationStruct{hostedOn}, result.missingRelations[1].others)
+	assert.Len(t, result.unresolvedRelations, 2)
+	assert.Equal(t, []string{"1"}, fetched, "relations fetched once per object")
+
+	_, err = reconcileChart(chart, func(string) ([]azure.FindStruct, error) { return nil, errors.New("bad page") }, nil)
+	assert.EqualError(t, err, "could not look up RIMS (endpoint IQ): bad page")
+}
+
+func TestRelationshipTypePair(t *testing.T) {
+	pac, ptc, capability := azure.ObjectTypeIdFor("Physical Application Component"), azure.ObjectTypeIdFor("Physical Technology Component"), azure.ObjectTypeIdFor("Capability")
+	uses := azure.RelationshipTypeStruct{Name: "uses", RelationshipTypePairs: []azure.RelationshipTypePair{
+		{RelationshipTypePairId: "pac-cap", LeadObjectTypeId: pac, MemberObjectTypeId: capability},
+		{RelationshipTypePairId: "pac-ptc", LeadObjectTypeId: pac, MemberObjectTypeId: ptc},
+	}}
+	pairId, sourceLeads, err := relationshipTypePair(uses, pac, ptc)
+	assert.NoError(t, err)
+	assert.Equal(t, "pac-ptc", pairId, "matches on both ends, not just the lead")
+	assert.True(t, sourceLeads)
+
+	pairId, sourceLeads, err = relationshipTypePair(uses, ptc, pac)
+	assert.NoError(t, err)
+	assert.Equal(t, "pac-ptc", pairId)
+	assert.False(t, sourceLeads)
+
+	_, _, err = relationshipTypePair(uses, ptc, capability)
+	assert.EqualError(t, err, "uses can't be between a Physical Technology Component and a Capability")
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	azure "vonexplaino.com/m/v2/vondiagram/azure"
+)
+
+/**
+** Relationship details - a relationship's own attributes, such as the
+** technology an interface uses, whether it's approved and who created and
+** last changed it. Its technology and label are what diagrams draw on the
+** relationship's line.
+**/
+
+var (
+	relationshipTechnology = "Interface Technology"
+	relationshipLabel      = "Label"
+)
+
+// The relationship attributes shown and edited, in order
+var relationshipAttributes = []formField{
+	{Label: "Technology", Attribute: relationshipTechnology, Widget: "string", MaxLength: 255},
+	{Label: "Data flow", Attribute: "Data Flow", Widget: "string", MaxLength: 255},
+	{Label: "Label", Attribute: relationshipLabel, Widget: "string", MaxLength: 255},
+	{Label: "Description", Attribute: "Description", Widget: "multiline", Lines: 4},
+}
+
+func relationAttribute(x azure.RelationStruct, name string) string {
+	for _, y := range x.AttributeValues {
+		if strings.EqualFold(y.AttributeName, name) {
+			return y.StringValue
+		}
+	}
+	return ""
+}
+
+// What a relationship's line on a diagram says: its label, or else its type's direction
+func relationLabel(x azure.RelationStruct) string {
+	if label := relationAttribute(x, relationshipLabel); label != "" {
+		return label
+	}
+	return x.RelationshipType.LeadToMemberDirection
+}
+
+func relationDetailValues(attributes []azure.AttributeValue) map[string]editValue {
+	toReturn := map[string]editValue{}
+	for _, x := range relationshipAttributes {
+		toReturn[x.Attribute] = editValue{kind: "string"}
+		for _, y := range attributes {
+			if strings.EqualFold(y.AttributeName, x.Attribute) {
+				toReturn[x.Attribute] = editValue{kind: "string", text: y.StringValue}
+			}
+		}
+	}
+	return toReturn
+}
+
+// The relationship with its saved attributes replaced
+func withRelationAttributes(x azure.RelationStruct, stringValues map[string]string) azure.RelationStruct {
+	attributes := []azure.AttributeValue{}
+	for _, y := range x.AttributeValues {
+		if _, ok := stringValues[y.AttributeName]; !ok {
+			attributes = append(attributes, y)
+		}
+	}
+	names := getMapStringKeys(stringValues)
+	sort.Strings(names)
+	for _, name := range names {
+		attributes = append(attributes, azure.AttributeValue{AttributeName: name, StringValue: stringValues[name]})
+	}
+	x.AttributeValues = attributes
+	return x
+}
+
+// When and by whom, as iServer has it
+func describeWhen(date, by string) string {
+	if date == "" {
+		return "Unknown"
+	}
+	date = strings.TrimSuffix(strings.Replace(date, "T", " ", 1), "Z")
+	if by == "" {
+		return date
+	}
+	return fmt.Sprintf("%s by %s", date, by)
+}
+
+// Who, by name where iServer gave one, otherwise by id
+func describeUser(x azure.UserStruct, id string) string {
+	if name := x.String(); name != "" {
+		return name
+	}
+	return id
+}
+
+// Show a relationship's details; onSaved gets it back with any changed attributes
+func showRelationshipDetailsWindow(x azure.RelationStruct, onSaved func(azure.RelationStruct)) {
+	detailsWindow := addWindowFor("Relationship: "+relationDescription(x), 600, 450)
+	detailsWindow.SetContent(widget.NewLabel("Loading..."))
+	detailsWindow.Show()
+
+	details, err := az.GetRelationshipDetails(x.RelationshipId)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("could not load the relationship: %w", err), detailsWindow)
+		return
+	}
+	original := relationDetailValues(details.AttributeValues)
+	labels := map[string]string{}
+	entries := map[string]*widget.Entry{}
+	form := widget.NewForm(
+		widget.NewFormItem("Created", widget.NewLabel(describeWhen(details.DateCreated, describeUser(details.CreatedBy, details.CreatedById)))),
+		widget.NewFormItem("Last changed", widget.NewLabel(describeWhen(details.LastModifiedDate, describeUser(details.LastModifiedBy, details.LastModifiedById)))),
+	)
+	approved := widget.NewCheck("Approved", func(bool) {})
+	approved.SetChecked(details.IsApproved)
+	if !details.RelationshipType.IsApprovable {
+		approved.Disable()
+	}
+	form.Append("Approval", approved)
+	for _, y := range relationshipAttributes {
+		entry := widget.NewEntry()
+		if y.Widget == "multiline" {
+			entry = widget.NewMultiLineEntry()
+			entry.Wrapping = fyne.TextWrapWord
+			entry.SetMinRowsVisible(y.Lines)
+		}
+		entry.SetText(original[y.Attribute].text)
+		entries[y.Attribute] = entry
+		labels[y.Attribute] = y.Label
+		form.Append(y.Label, entry)
+	}
+
+	save := widget.NewButton("Save", func() {
+		current := map[string]editValue{}
+		for _, y := range relationshipAttributes {
+			current[y.Attribute] = editValue{kind: "string", text: entries[y.Attribute].Text}
+			if y.MaxLength > 0 && len(entries[y.Attribute].Text) > y.MaxLength {
+				dialog.ShowError(fmt.Errorf("%s can be at most %d characters", y.Label, y.MaxLength), detailsWindow)
+				return
+			}
+		}
+		changes := diffEditValues(original, current, labels)
+		summary := editChangeSummary(changes)
+		if approved.Checked != details.IsApproved {
+			summary = strings.TrimSpace(fmt.Sprintf("%s\nApproved: %t → %t", summary, details.IsApproved, approved.Checked))
+		} else if len(changes) == 0 {
+			dialog.ShowInformation("Save", "Nothing has changed", detailsWindow)
+			return
+		}
+		dialog.ShowConfirm("Save", "Are you sure you want to commit these changes?\n\n"+summary, func(ok bool) {
+			if !ok {
+				return
+			}
+			stringValues, _, _ := editChangeValues(changes)
+			success, message := az.SaveRelationshipDetails(x.RelationshipId, stringValues, approved.Checked)
+			if !success {
+				dialog.ShowInformation("Save failed", message, detailsWindow)
+				return
+			}
+			original = current
+			details.IsApproved = approved.Checked
+			x = withRelationAttributes(x, stringValues)
+			onSaved(x)
+			dialog.ShowInformation("Save Succesful", message, detailsWindow)
+		}, detailsWindow)
+	})
+
+	detailsWindow.SetContent(container.NewBorder(
+		widget.NewLabelWithStyle(relationDescription(x), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		save,
+		nil,
+		nil,
+		container.NewVScroll(form),
+	))
+}
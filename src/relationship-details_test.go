@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	azure "vonexplaino.com/m/v2/vondiagram/azure"
+)
+
+func TestRelationDetails(t *testing.T) {
+	x := relationFor("r1", "canvas", "db")
+	assert.Equal(t, "uses", relationLabel(x))
+	assert.Equal(t, "", relationAttribute(x, relationshipTechnology))
+
+	x.AttributeValues = []azure.AttributeValue{
+		{AttributeName: "interface technology", StringValue: "JDBC"},
+		{AttributeName: "Owner", StringValue: "Pat"},
+	}
+	assert.Equal(t, "JDBC", relationAttribute(x, relationshipTechnology))
+	values := relationDetailValues(x.AttributeValues)
+	assert.Equal(t, "JDBC", values[relationshipTechnology].text)
+	assert.Equal(t, editValue{kind: "string"}, values[relationshipLabel])
+	assert.Len(t, values, len(relationshipAttributes))
+
+	x = withRelationAttributes(x, map[string]string{relationshipLabel: "reads grades", "interface technology": "ODBC"})
+	assert.Equal(t, "reads grades", relationLabel(x))
+	assert.Equal(t, "ODBC", relationAttribute(x, relationshipTechnology))
+	assert.Equal(t, "Pat", relationAttribute(x, "Owner"))
+
+	assert.Equal(t, "Unknown", describeWhen("", "abc"))
+	assert.Equal(t, "2024-03-01 10:00:00 by abc", describeWhen("2024-03-01T10:00:00Z", "abc"))
+	assert.Equal(t, "Pat Smith", describeUser(azure.UserStruct{FirstName: "Pat", LastName: "Smith", Email: "pat@example.com"}, "abc"))
+	assert.Equal(t, "pat@example.com", describeUser(azure.UserStruct{Email: "pat@example.com"}, "abc"))
+	assert.Equal(t, "abc", describeUser(azure.UserStruct{}, "abc"))
+}
+
+func TestDiagramRelationTechnology(t *testing.T) {
+	basics := azure.IServerObjectStruct{ObjectId: "canvas", Name: "canvas"}
+	x := relationFor("r1", "canvas", "db")
+	x.AttributeValues = []azure.AttributeValue{
+		{AttributeName: relationshipTechnology, StringValue: "JDBC"},
+		{AttributeName: relationshipLabel, StringValue: "reads grades"},
+	}
+	puml := diagramPlantUML(basics, map[string]azure.RelationStruct{"r1": x}, diagramDefinition{})
+	assert.Contains(t, puml, "Rel(canvas,db,\"reads grades\",\"JDBC\")\n")
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	azure "vonexplaino.com/m/v2/vondiagram/azure"
+)
+
+/**
+** Relationship matrix - the relationship types iServer allows between each
+** pair of object types, and which end leads. Building it asks iServer about
+** every pair, so it's kept as relationship-matrix.json in the user config
+** directory's vondiagram folder and only rebuilt on request. Once built it
+** limits what the add relationship dialogs offer, and a domain's existing
+** relationships can be checked against it.
+**/
+
+type matrixRelationship struct {
+	RelationshipTypeId     string `json:"RelationshipTypeId"`
+	RelationshipTypePairId string `json:"RelationshipTypePairId"`
+	Name                   string `json:"Name"`
+	Direction              string `json:"Direction"`
+}
+
+// Allowed relationships by lead object type, then member object type
+type relationshipMatrix map[string]map[string][]matrixRelationship
+
+type matrixProblem struct {
+	relation azure.RelationStruct
+	message  string
+}
+
+var loadedRelationshipMatrix relationshipMatrix
+
+// Ask about every pair of types once, filing each type pair under the type that leads it
+func buildRelationshipMatrix(
+	types []string,
+	fetch func(objectTypeId1, objectTypeId2 string) map[string]azure.RelationshipTypeStruct,
+	progress func(done, total int),
+) relationshipMatrix {
+	toReturn := relationshipMatrix{}
+	add := func(lead, member string, x azure.RelationshipTypeStruct, pairId string) {
+		if toReturn[lead] == nil {
+			toReturn[lead] = map[string][]matrixRelationship{}
+		}
+		for _, y := range toReturn[lead][member] {
+			if y.RelationshipTypePairId == pairId {
+				return
+			}
+		}
+		toReturn[lead][member] = append(toReturn[lead][member], matrixRelationship{
+			RelationshipTypeId:     x.RelationshipTypeId,
+			RelationshipTypePairId: pairId,
+			Name:                   x.Name,
+			Direction:              x.Direction,
+		})
+	}
+	total := len(types) * (len(types) + 1) / 2
+	done := 0
+	for i, first := range types {
+		for _, second := range types[i:] {
+			firstId, secondId := azure.ObjectTypeIdFor(first), azure.ObjectTypeIdFor(second)
+			for _, x := range fetch(firstId, secondId) {
+				// A type can have pairs for other object types too, so both ends have to match
+				for _, y := range x.RelationshipTypePairs {
+					switch {
+					case y.LeadObjectTypeId == firstId && y.MemberObjectTypeId == secondId:
+						add(first, second, x, y.RelationshipTypePairId)
+					case y.LeadObjectTypeId == secondId && y.MemberObjectTypeId == firstId:
+						add(second, first, x, y.RelationshipTypePairId)
+					}
+				}
+			}
+			done++
+			progress(done, total)
+		}
+	}
+	for _, x := range toReturn {
+		for _, y := range x {
+			sort.Slice(y, func(i, j int) bool { return y[i].Name < y[j].Name })
+		}
+	}
+	return toReturn
+}
+
+func (m relationshipMatrix) permits(lead, member, relationshipTypeId string) bool {
+	for _, x := range m[lead][member] {
+		if x.RelationshipTypeId == relationshipTypeId {
+			return true
+		}
+	}
+	return false
+}
+
+// The types with anything allowed to or from the type
+func (m relationshipMatrix) relatableTypes(objectType string) []string {
+	toReturn := []string{}
+	for _, x := range azure.ObjectTypeNames() {
+		if len(m[objectType][x]) > 0 || len(m[x][objectType]) > 0 {
+			toReturn = append(toReturn, x)
+		}
+	}
+	return toReturn
+}
+
+// The relationship types allowed between the two, with only the pairs allowed
+func (m relationshipMatrix) relationshipTypes(source, target string) map[string]azure.RelationshipTypeStruct {
+	toReturn := map[string]azure.RelationshipTypeStruct{}
+	add := func(lead, member string, x matrixRelationship) {
+		y, ok := toReturn[x.RelationshipTypeId]
+		if !ok {
+			y = azure.RelationshipTypeStruct{RelationshipTypeId: x.RelationshipTypeId, Name: x.Name, Direction: x.Direction, ActiveState: true}
+		}
+		y.RelationshipTypePairs = append(y.RelationshipTypePairs, azure.RelationshipTypePair{
+			RelationshipTypePairId: x.RelationshipTypePairId,
+			LeadObjectTypeId:       azure.ObjectTypeIdFor(lead),
+			MemberObjectTypeId:     azure.ObjectTypeIdFor(member),
+		})
+		toReturn[x.RelationshipTypeId] = y
+	}
+	for _, x := range m[source][target] {
+		add(source, target, x)
+	}
+	if source != target {
+		for _, x := range m[target][source] {
+			add(target, source, x)
+		}
+	}
+	return toReturn
+}
+
+// What the type can lead (→) and be led by (←), one line per other type
+func (m relationshipMatrix) lines(objectType string) []string {
+	names := func(x []matrixRelationship) string {
+		toReturn := []string{}
+		for _, y := range x {
+			toReturn = append(toReturn, y.Name)
+		}
+		return strings.Join(toReturn, ", ")
+	}
+	toReturn := []string{}
+	for _, x := range azure.ObjectTypeNames() {
+		if len(m[objectType][x]) > 0 {
+			toReturn = append(toReturn, fmt.Sprintf("→ %s: %s", x, names(m[objectType][x])))
+		}
+	}
+	for _, x := range azure.ObjectTypeNames() {
+		if len(m[x][objectType]) > 0 {
+			toReturn = append(toReturn, fmt.Sprintf("← %s: %s", x, names(m[x][objectType])))
+		}
+	}
+	return toReturn
+}
+
+// Relationships the matrix doesn't allow; those between types it doesn't know are left alone
+func (m relationshipMatrix) validate(relations []azure.RelationStruct) []matrixProblem {
+	toReturn := []matrixProblem{}
+	for _, x := range relations {
+		lead, member := x.LeadObject.Type.Name, x.MemberObject.Type.Name
+		if azure.ObjectTypeIdFor(lead) == "" || azure.ObjectTypeIdFor(member) == "" || x.RelationshipType.RelationshipTypeId == "" {
+			continue
+		}
+		if !m.permits(lead, member, x.RelationshipType.RelationshipTypeId) {
+			toReturn = append(toReturn, matrixProblem{
+				relation: x,
+				message:  fmt.Sprintf("%q isn't allowed from a %s to a %s", x.RelationshipType.Name, lead, member),
+			})
+		}
+	}
+	sort.SliceStable(toReturn, func(i, j int) bool {
+		return relationDescription(toReturn[i].relation) < relationDescription(toReturn[j].relation)
+	})
+	return toReturn
+}
+
+func relationshipMatrixFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "vondiagram", "relationship-matrix.json")
+}
+
+func loadRelationshipMatrix(fileName string) (relationshipMatrix, error) {
+	toReturn := relationshipMatrix{}
+	contents, err := os.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		return toReturn, nil
+	}
+	if err != nil {
+		return toReturn, err
+	}
+	err = json.Unmarshal(contents, &toReturn)
+	return toReturn, err
+}
+
+func saveRelationshipMatrix(fileName string, m relationshipMatrix) error {
+	asJson, err := json.MarshalIndent(m, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, asJson, 0644)
+}
+
+func currentRelationshipMatrix() relationshipMatrix {
+	if loadedRelationshipMatrix == nil {
+		var err error
+		loadedRelationshipMatrix, err = loadRelationshipMatrix(relationshipMatrixFile())
+		if err != nil {
+			fyne.LogError("Could not load the relationship matrix", err)
+		}
+	}
+	return loadedRelationshipMatrix
+}
+
+// Types the add relationship dialogs offer; all of them until the matrix is built
+func relatableTypes(objectType string) []string {
+	if m := currentRelationshipMatrix(); len(m) > 0 {
+		return m.relatableTypes(objectType)
+	}
+	return azure.ObjectTypeNames()
+}
+
+// Relationship types the add relationship dialogs offer, from the matrix once it's built
+func relationshipTypesBetween(source, target string) map[string]azure.RelationshipTypeStruct {
+	if m := currentRelationshipMatrix(); len(m) > 0 {
+		return m.relationshipTypes(source, target)
+	}
+	return az.GetRelationTypesForObjectType(azure.ObjectTypeIdFor(source), azure.ObjectTypeIdFor(target))
+}
+
+// Every relationship of the domain's objects that the matrix doesn't allow
+func checkDomainRelations(domain string, m relationshipMatrix) ([]matrixProblem, error) {
+	objects, err := az.GetDomainObjects(domain, azure.SearchObjectTypes, []string{}, true)
+	if err != nil {
+		return nil, err
+	}
+	relations := []azure.RelationStruct{}
+	seen := map[string]bool{}
+	for i, x := range objects {
+		UpdateMessage(fmt.Sprintf("Checking %d of %d", i+1, len(objects)))
+		for _, y := range az.FindRelations(x.ObjectId) {
+			if !seen[y.RelationshipId] {
+				seen[y.RelationshipId] = true
+				relations = append(relations, y)
+			}
+		}
+	}
+	return m.validate(relations), nil
+}
+
+func showRelationshipMatrixWindow() {
+	matrixWindow := addWindowFor("Relationship matrix", 700, 600)
+	summary := widget.NewLabel("")
+	allowed := widget.NewLabel("")
+	allowed.Wrapping = fyne.TextWrapWord
+	objectType := widget.NewSelect(azure.ObjectTypeNames(), func(picked string) {
+		allowed.SetText(strings.Join(currentRelationshipMatrix().lines(picked), "\n"))
+	})
+	showSummary := func() {
+		if len(currentRelationshipMatrix()) == 0 {
+			summary.SetText("Not built yet; the add relationship dialogs ask iServer each time")
+		} else {
+			summary.SetText(fmt.Sprintf("Built for %d object types", len(azure.ObjectTypeNames())))
+		}
+		if objectType.Selected != "" {
+			objectType.OnChanged(objectType.Selected)
+		}
+	}
+	showSummary()
+
+	rebuild := widget.NewButton("Rebuild from iServer", func() {
+		go func() {
+			built := buildRelationshipMatrix(azure.ObjectTypeNames(), az.GetRelationTypesForObjectType, func(done, total int) {
+				UpdateMessage(fmt.Sprintf("Matrix %d of %d", done, total))
+			})
+			UpdateMessage("Ready")
+			if err := saveRelationshipMatrix(relationshipMatrixFile(), built); err != nil {
+				dialog.ShowError(err, matrixWindow)
+			}
+			loadedRelationshipMatrix = built
+			showSummary()
+		}()
+	})
+	check := widget.NewButton("Check domain", func() {
+		domain := myApp.Preferences().StringWithFallback("Department", "")
+		if domain == "" {
+			dialog.ShowInformation("Relationship matrix", "Choose a domain in Settings first", matrixWindow)
+			return
+		}
+		if len(currentRelationshipMatrix()) == 0 {
+			dialog.ShowInformation("Relationship matrix", "Build the matrix first", matrixWindow)
+			return
+		}
+		go func() {
+			problems, err := checkDomainRelations(domain, currentRelationshipMatrix())
+			UpdateMessage("Ready")
+			if err != nil {
+				dialog.ShowError(err, matrixWindow)
+				return
+			}
+			showMatrixProblemsWindow(domain, problems)
+		}()
+	})
+
+	matrixWindow.SetContent(container.NewBorder(
+		container.NewVBox(
+			summary,
+			container.NewGridWithColumns(2, rebuild, check),
+			widget.NewForm(widget.NewFormItem("Object type", objectType)),
+		),
+		nil,
+		nil,
+		nil,
+		container.NewVScroll(allowed),
+	))
+	matrixWindow.Show()
+}
+
+func showMatrixProblemsWindow(domain string, problems []matrixProblem) {
+	problemsWindow := addWindowFor("Relationships not allowed in "+domain, 800, 500)
+	list := widget.NewList(
+		func() int { return len(problems) },
+		func() fyne.CanvasObject { return widget.NewLabel("template") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			item.(*widget.Label).SetText(fmt.Sprintf("%s: %s", relationDescription(problems[id].relation), problems[id].message))
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) {
+		openbrowser(fmt.Sprintf(iServerObjectURL, problems[id].relation.LeadObjectId))
+		list.UnselectAll()
+	}
+	problemsWindow.SetContent(container.NewBorder(
+		widget.NewLabel(fmt.Sprintf("%d relationships aren't allowed. Click one to open its lead object in iServer.", len(problems))),
+		nil,
+		nil,
+		nil,
+		list,
+	))
+	problemsWindow.Show()
+}
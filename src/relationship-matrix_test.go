@@ -0,0 +1,78 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	azure "vonexplaino.com/m/v2/vondiagram/azure"
+)
+
+func TestRelationshipMatrix(t *testing.T) {
+	pac, ptc, cap := "Physical Application Component", "Physical Technology Component", "Capability"
+	asked := 0
+	matrix := buildRelationshipMatrix([]string{cap, pac, ptc}, func(objectTypeId1, objectTypeId2 string) map[string]azure.RelationshipTypeStruct {
+		asked++
+		ids := map[string]bool{objectTypeId1: true, objectTypeId2: true}
+		switch {
+		case ids[azure.ObjectTypeIdFor(pac)] && ids[azure.ObjectTypeIdFor(ptc)]:
+			// A PAC leads the supports type's only pair, but to a Capability
+			supports := azure.RelationshipTypeStruct{RelationshipTypeId: "supports", Name: "supports"}
+			supports.RelationshipTypePairs = []azure.RelationshipTypePair{
+				{RelationshipTypePairId: "pac-cap", LeadObjectTypeId: azure.ObjectTypeIdFor(pac), MemberObjectTypeId: azure.ObjectTypeIdFor(cap)},
+			}
+			return map[string]azure.RelationshipTypeStruct{"hosted": hostedOn(), "supports": supports}
+		case ids[azure.ObjectTypeIdFor(pac)] && ids[azure.ObjectTypeIdFor(cap)]:
+			x := azure.RelationshipTypeStruct{RelationshipTypeId: "realises", Name: "realises"}
+			x.RelationshipTypePairs = []azure.RelationshipTypePair{
+				{RelationshipTypePairId: "pac-cap", LeadObjectTypeId: azure.ObjectTypeIdFor(pac), MemberObjectTypeId: azure.ObjectTypeIdFor(cap)},
+			}
+			return map[string]azure.RelationshipTypeStruct{"realises": x}
+		}
+		return map[string]azure.RelationshipTypeStruct{}
+	}, func(done, total int) {
+		assert.Equal(t, 6, total)
+	})
+	assert.Equal(t, 6, asked)
+	assert.True(t, matrix.permits(pac, ptc, "hosted"))
+	assert.False(t, matrix.permits(ptc, pac, "hosted"))
+	assert.False(t, matrix.permits(pac, cap, "hosted"))
+	assert.False(t, matrix.permits(pac, ptc, "supports"), "filed by both ends of the pair")
+	assert.Equal(t, []string{cap, ptc}, matrix.relatableTypes(pac))
+	assert.Equal(t, []string{pac}, matrix.relatableTypes(ptc))
+	assert.Equal(t, []string{"→ Capability: realises", "→ Physical Technology Component: is hosted on"}, matrix.lines(pac))
+	assert.Equal(t, []string{"← Physical Application Component: is hosted on"}, matrix.lines(ptc))
+
+	// The dialogs get only allowed types, with the pair for the way round the matrix allows
+	types := matrix.relationshipTypes(ptc, pac)
+	assert.Len(t, types, 1)
+	pairId, sourceLeads, err := relationshipTypePair(types["hosted"], azure.ObjectTypeIdFor(ptc), azure.ObjectTypeIdFor(pac))
+	assert.NoError(t, err)
+	assert.Equal(t, "pac-ptc", pairId)
+	assert.False(t, sourceLeads)
+	assert.Empty(t, matrix.relationshipTypes(ptc, cap))
+
+	good := relationFor("r1", "canvas", "server")
+	good.RelationshipType.RelationshipTypeId = "hosted"
+	good.LeadObject.Type.Name, good.MemberObject.Type.Name = pac, ptc
+	bad := relationFor("r2", "canvas", "teaching")
+	bad.RelationshipType.RelationshipTypeId = "hosted"
+	bad.RelationshipType.Name = "is hosted on"
+	bad.LeadObject.Type.Name, bad.MemberObject.Type.Name = pac, cap
+	unknown := relationFor("r3", "canvas", "thing")
+	unknown.RelationshipType.RelationshipTypeId = "hosted"
+	unknown.LeadObject.Type.Name, unknown.MemberObject.Type.Name = pac, "Widget"
+	problems := matrix.validate([]azure.RelationStruct{good, bad, unknown})
+	assert.Len(t, problems, 1)
+	assert.Equal(t, "r2", problems[0].relation.RelationshipId)
+	assert.Equal(t, `"is hosted on" isn't allowed from a Physical Application Component to a Capability`, problems[0].message)
+
+	fileName := filepath.Join(t.TempDir(), "vondiagram", "relationship-matrix.json")
+	loaded, err := loadRelationshipMatrix(fileName)
+	assert.NoError(t, err)
+	assert.Empty(t, loaded)
+	assert.NoError(t, saveRelationshipMatrix(fileName, matrix))
+	loaded, err = loadRelationshipMatrix(fileName)
+	assert.NoError(t, err)
+	assert.Equal(t, matrix, loaded)
+}
@@ -2,8 +2,6 @@ package main
 
 import (
 	"fmt"
-	"io"
-	"os"
 	"path/filepath"
 	"strings"
 
@@ -25,27 +23,57 @@ func addRelationship(
 	rightObject azure.FindStruct,
 	relationshipId string,
 	connection string,
+	technology string,
+	groupTypes map[string]int,
 ) {
 	objectsRef := (*objects)
-	if leftObject.Type.Name == "Location" {
-		objectsRef[leftAlias].children[rightObject.ObjectId] = (*objects)[rightAlias]
+	leftRank, rightRank := groupTypes[leftObject.Type.Name], groupTypes[rightObject.Type.Name]
+	leftGroup, leftFound := findDrawnObject(objectsRef, leftAlias)
+	rightGroup, rightFound := findDrawnObject(objectsRef, rightAlias)
+	_, leftTop := objectsRef[leftAlias]
+	_, rightTop := objectsRef[rightAlias]
+	// Grouping objects swallow the other end when it is finer grained and not already inside a group
+	if leftRank > 0 && (rightRank == 0 || leftRank < rightRank) && leftFound && rightTop {
+		leftGroup.children[rightObject.ObjectId] = objectsRef[rightAlias]
 		delete(objectsRef, rightAlias)
-	} else if rightObject.Type.Name == "Location" {
-		objectsRef[rightAlias].children[leftObject.ObjectId] = (*objects)[leftAlias]
+	} else if rightRank > 0 && (leftRank == 0 || rightRank < leftRank) && rightFound && leftTop {
+		rightGroup.children[leftObject.ObjectId] = objectsRef[leftAlias]
 		delete(objectsRef, leftAlias)
 	} else {
 		(*relationships)[relationshipId] = relationshipStruct{
 			leftAlias:        leftAlias,
 			rightAlias:       rightAlias,
 			relationshipName: connection,
+			technology:       technology,
 		}
 	}
 }
 
+// Find an object whether it is at the top of the diagram or already inside a group
+func findDrawnObject(objects map[string]objectStruct, alias string) (objectStruct, bool) {
+	if x, ok := objects[alias]; ok {
+		return x, true
+	}
+	for _, x := range objects {
+		for _, y := range x.children {
+			if y.alias == alias {
+				return y, true
+			}
+		}
+		if y, ok := findDrawnObject(x.children, alias); ok {
+			return y, true
+		}
+	}
+	return objectStruct{}, false
+}
+
 func createRelationshipList(
 	selectedRelations map[string]azure.RelationStruct,
 	knownKids map[widget.TreeNodeID][]widget.TreeNodeID,
-	knownBits map[widget.TreeNodeID]azure.RelationStruct) *widget.Tree {
+	knownBits map[widget.TreeNodeID]azure.RelationStruct,
+	expandedObjects map[string]bool,
+	directions map[string]string,
+	onToggle func()) *widget.Tree {
 	return widget.NewTree(
 		func(id widget.TreeNodeID) []widget.TreeNodeID {
 			y, x := knownKids[id]
@@ -59,7 +87,16 @@ func createRelationshipList(
 			return here
 		},
 		func(branch bool) fyne.CanvasObject {
-			return container.NewHBox(widget.NewCheck("Diag", func(value bool) {}))
+			return container.NewBorder(
+				nil,
+				nil,
+				nil,
+				container.NewHBox(
+					widget.NewButtonWithIcon("", theme.InfoIcon(), func() {}),
+					widget.NewSelect(append([]string{"Auto"}, relationDirections...), func(s string) {}),
+				),
+				widget.NewCheck("Diag", func(value bool) {}),
+			)
 		},
 		func(id widget.TreeNodeID, branch bool, item fyne.CanvasObject) {
 			checkbox := &(item.(*fyne.Container).Objects[0])
@@ -68,9 +105,20 @@ func createRelationshipList(
 					selectedRelations[knownBits[id].RelationshipId] = knownBits[id]
 					_, here := knownKids[knownBits[id].RelationshipId]
 					if !here {
+						// Only fetch the ends that haven't been looked at yet
+						toFetch := []string{}
+						for _, objectId := range []string{knownBits[id].LeadObjectId, knownBits[id].MemberObjectId} {
+							if !expandedObjects[objectId] {
+								expandedObjects[objectId] = true
+								toFetch = append(toFetch, objectId)
+							}
+						}
+						knownKids[knownBits[id].RelationshipId] = []widget.TreeNodeID{}
 						go func() {
-							knownKids[knownBits[id].RelationshipId] = []widget.TreeNodeID{}
-							rels := append(az.FindRelations(knownBits[id].LeadObjectId), az.FindRelations(knownBits[id].MemberObjectId)...)
+							rels := []azure.RelationStruct{}
+							for _, objectId := range toFetch {
+								rels = append(rels, az.FindRelations(objectId)...)
+							}
 							for _, x := range rels {
 								_, here2 := knownBits[x.RelationshipId]
 								if !here2 {
@@ -83,6 +131,7 @@ func createRelationshipList(
 				} else {
 					delete(selectedRelations, knownBits[id].RelationshipId)
 				}
+				onToggle()
 			}
 			(*checkbox).(*widget.Check).Text = (fmt.Sprintf(
 				"%s %s %s",
@@ -93,6 +142,31 @@ func createRelationshipList(
 			_, x := selectedRelations[knownBits[id].RelationshipId]
 			(*checkbox).(*widget.Check).SetChecked(x)
 			(*checkbox).Refresh()
+			buttons := item.(*fyne.Container).Objects[1].(*fyne.Container)
+			buttons.Objects[0].(*widget.Button).OnTapped = func() {
+				showRelationshipDetailsWindow(knownBits[id], func(saved azure.RelationStruct) {
+					knownBits[id] = saved
+					if _, ok := selectedRelations[saved.RelationshipId]; ok {
+						selectedRelations[saved.RelationshipId] = saved
+					}
+					onToggle()
+				})
+			}
+			direction := buttons.Objects[1].(*widget.Select)
+			direction.OnChanged = nil
+			if len(directions[knownBits[id].RelationshipId]) > 0 {
+				direction.SetSelected(directions[knownBits[id].RelationshipId])
+			} else {
+				direction.SetSelected("Auto")
+			}
+			direction.OnChanged = func(value string) {
+				if value == "Auto" {
+					delete(directions, knownBits[id].RelationshipId)
+				} else {
+					directions[knownBits[id].RelationshipId] = value
+				}
+				onToggle()
+			}
 		},
 	)
 }
@@ -103,10 +177,76 @@ func createRelationshipWindow(
 	knownKids map[widget.TreeNodeID][]widget.TreeNodeID,
 	knownBits map[widget.TreeNodeID]azure.RelationStruct,
 	thenWindow *fyne.Window) *fyne.Container {
+	expandedObjects := map[string]bool{basics.ObjectId: true}
+	definition := newDiagramDefinition(basics)
+	preview := newDiagramPreview()
+	onToggle := func() {
+		preview.Update(basics, selectedRelations, *definition)
+	}
 	relationshipList := createRelationshipList(
 		selectedRelations,
 		knownKids,
-		knownBits)
+		knownBits,
+		expandedObjects,
+		definition.Directions,
+		onToggle)
+	relationshipSplit := container.NewVSplit(relationshipList, preview.holder)
+	askToSave := func() {
+		filename := widget.NewEntry()
+		formats := widget.NewCheckGroup([]string{"puml", "png", "svg"}, func(s []string) {})
+		formats.Horizontal = true
+		formats.SetSelected(definition.Formats)
+		dialog.ShowForm(
+			"Save diagram",
+			"Save",
+			"Don't",
+			[]*widget.FormItem{
+				widget.NewFormItem("Filename", filename),
+				widget.NewFormItem("Formats", formats),
+			},
+			func(save bool) {
+				if !save {
+					return
+				}
+				fileBase := filepath.Join(getSavePath(), filepath.Base(filename.Text))
+				fileBase = strings.TrimSuffix(fileBase, ".puml")
+				definition.Formats = formats.Selected
+				if len(definition.Formats) == 0 {
+					definition.Formats = []string{"puml"}
+				}
+				written, err := saveDiagram(fileBase, basics, selectedRelations, *definition)
+				if err != nil {
+					dialog.ShowError(err, *thenWindow)
+					return
+				}
+				dialog.ShowInformation(
+					"Saved",
+					fmt.Sprintf("Saved the diagram to\n%s", strings.Join(written, "\n")),
+					*thenWindow,
+				)
+			},
+			*thenWindow,
+		)
+	}
+	showExplored := func(result explorerResult) {
+		knownKids = map[widget.TreeNodeID][]widget.TreeNodeID{}
+		knownBits = map[widget.TreeNodeID]azure.RelationStruct{}
+		for i, x := range result.children {
+			knownKids[i] = x
+		}
+		for i, x := range result.relations {
+			knownBits[i] = x
+		}
+		expandedObjects = result.fetched
+		relationshipSplit.Leading = createRelationshipList(
+			selectedRelations,
+			knownKids,
+			knownBits,
+			expandedObjects,
+			definition.Directions,
+			onToggle)
+		relationshipSplit.Refresh()
+	}
 	var returningContainer *fyne.Container
 	returningContainer = container.NewBorder(
 		widget.NewToolbar(
@@ -114,77 +254,20 @@ func createRelationshipWindow(
 				theme.ContentAddIcon(),
 				func() {
 					addRelWindow := addWindowFor("Add Relationship", 500, 250)
-					objectType := widget.NewSelectEntry([]string{
-						"Actor",
-						"Application Service",
-						"Business Service",
-						"Capability",
-						"Constraint",
-						"Data Entity",
-						"Interface",
-						"Location",
-						"Logical Application Component",
-						"Logical Data Component",
-						"Logical Technology Component",
-						"Organization Unit",
-						"Physical Application Component",
-						"Physical Data Component",
-						"Physical Technology Component",
-						"Physical Technology Group",
-						"Principle",
-						"Process",
-						"Product",
-						"Requirement",
-						"Risk",
-						"Role",
-						"Technology Service",
-					})
-					objectTypesList := map[string]string{
-						"Actor":                          "445f5bb2-2eef-e811-9f2b-00155d26bcf8",
-						"Application Service":            "bc5f5bb2-2eef-e811-9f2b-00155d26bcf8",
-						"Business Service":               "73d7af8c-5e52-ea11-a94c-28187852a561",
-						"Capability":                     "265f5bb2-2eef-e811-9f2b-00155d26bcf8",
-						"Constraint":                     "535f5bb2-2eef-e811-9f2b-00155d26bcf8",
-						"Data Entity":                    "625f5bb2-2eef-e811-9f2b-00155d26bcf8",
-						"Interface":                      "a3b624e4-b642-ea11-a601-28187852aafd",
-						"Location":                       "cb5f5bb2-2eef-e811-9f2b-00155d26bcf8",
-						"Logical Application Component":  "7cb624e4-b642-ea11-a601-28187852aafd",
-						"Logical Data Component":         "96b624e4-b642-ea11-a601-28187852aafd",
-						"Logical Technology Component":   "070714ec-b642-ea11-a601-28187852aafd",
-						"Organization Unit":              "b0b624e4-b642-ea11-a601-28187852aafd",
-						"Physical Application Component": "6fb624e4-b642-ea11-a601-28187852aafd",
-						"Physical Data Component":        "37395db8-2eef-e811-9f2b-00155d26bcf8",
-						"Physical Technology Component":  "140714ec-b642-ea11-a601-28187852aafd",
-						"Physical Technology Group":      "5171e716-436d-ee11-9942-00224895c2e5",
-						"Principle":                      "70395db8-2eef-e811-9f2b-00155d26bcf8",
-						"Process":                        "7f395db8-2eef-e811-9f2b-00155d26bcf8",
-						"Product":                        "8e395db8-2eef-e811-9f2b-00155d26bcf8",
-						"Requirement":                    "9d395db8-2eef-e811-9f2b-00155d26bcf8",
-						"Risk":                           "b65f6dbe-2eef-e811-9f2b-00155d26bcf8",
-						"Role":                           "243a5db8-2eef-e811-9f2b-00155d26bcf8",
-						"Technology Service":             "52395db8-2eef-e811-9f2b-00155d26bcf8",
-					}
+					objectType := widget.NewSelectEntry(relatableTypes(basics.ObjectType.Name))
 					relationshipSelect := widget.NewSelectEntry([]string{})
-					relationshipTypesList := map[string]struct {
-						id               string
-						typepair         string
-						leadobjecttypeid string
-					}{}
+					relationshipTypesList := map[string]azure.RelationshipTypeStruct{}
 					objectSelect := widget.NewSelectEntry([]string{})
 					objectSelectList := map[string]string{}
 
 					handleObjectTypeChange := func(ch string) {
-						mike := az.GetRelationTypesForObjectType(
-							basics.ObjectType.Id,
-							objectTypesList[objectType.Text],
+						mike := relationshipTypesBetween(
+							basics.ObjectType.Name,
+							objectType.Text,
 						)
 						selects := []string{}
-						for id, obj := range mike {
-							relationshipTypesList[obj.Name] = struct {
-								id               string
-								typepair         string
-								leadobjecttypeid string
-							}{id, obj.RelationshipTypePairs[0].RelationshipTypePairId, obj.RelationshipTypePairs[0].LeadObjectTypeId}
+						for _, obj := range mike {
+							relationshipTypesList[obj.Name] = obj
 							selects = append(selects, obj.Name)
 						}
 						relationshipSelect.SetOptions(selects)
@@ -197,32 +280,29 @@ func createRelationshipWindow(
 								widget.NewToolbarAction(
 									theme.DocumentSaveIcon(),
 									func() {
+										if _, ok := relationshipTypesList[relationshipSelect.Text]; !ok {
+											dialog.ShowInformation("Add Relationship", "Choose a relationship from the list", addRelWindow)
+											return
+										}
 										leadObject := basics.ObjectId
 										memberObject := objectSelectList[objectSelect.Text]
-										if basics.ObjectType.Id != relationshipTypesList[relationshipSelect.Text].leadobjecttypeid {
+										pairId, thisLeads, err := relationshipTypePair(
+											relationshipTypesList[relationshipSelect.Text],
+											basics.ObjectType.Id,
+											azure.ObjectTypeIdFor(objectType.Text),
+										)
+										if !thisLeads {
 											leadObject = objectSelectList[objectSelect.Text]
 											memberObject = basics.ObjectId
-
 										}
-										path := "/odata/Relationships"
-										query := ``
-										body := fmt.Sprintf(
-											`{"RelationshipTypeId":"%s",
-											"ModelId":"%s",
-											"RelationshipTypePairId":"%s",
-											"LeadModelItemId":"%s",
-											"MemberModelItemId":"%s"}`,
-											relationshipTypesList[relationshipSelect.Text].id,
-											azure.BaselineArchitectureModel,
-											relationshipTypesList[relationshipSelect.Text].typepair,
-											leadObject,
-											memberObject,
-										)
-										mep, err := az.CallRestEndpoint(
-											"POST",
-											path,
-											[]byte(body),
-											query)
+										if err == nil {
+											err = az.CreateRelationship(
+												relationshipTypesList[relationshipSelect.Text].RelationshipTypeId,
+												pairId,
+												leadObject,
+												memberObject,
+											)
+										}
 										if err != nil {
 											dialog.ShowInformation(
 												"Failed to save",
@@ -230,20 +310,11 @@ func createRelationshipWindow(
 												*thenWindow,
 											)
 										} else {
-											_, err2 := io.ReadAll(mep)
-											if err2 != nil {
-												dialog.ShowInformation(
-													"Failed to save",
-													err2.Error(),
-													*thenWindow,
-												)
-											} else {
-												dialog.ShowInformation(
-													"Save success",
-													"",
-													*thenWindow,
-												)
-											}
+											dialog.ShowInformation(
+												"Save success",
+												"",
+												*thenWindow,
+											)
 										}
 										addRelWindow.Close()
 									},
@@ -274,7 +345,7 @@ func createRelationshipWindow(
 										func() {
 											az.FindMeInTypeThen(
 												objectSelect.Text,
-												objectTypesList[objectType.Text],
+												azure.ObjectTypeIdFor(objectType.Text),
 												func(finds []azure.FindStruct) {
 													returns := []string{}
 													objectSelectList = map[string]string{}
@@ -284,17 +355,13 @@ func createRelationshipWindow(
 													}
 													objectSelect.SetOptions(returns)
 												})
-											mike := az.GetRelationTypesForObjectType(
-												basics.ObjectType.Id,
-												objectTypesList[objectType.Text],
+											mike := relationshipTypesBetween(
+												basics.ObjectType.Name,
+												objectType.Text,
 											)
 											selects := []string{}
-											for id, obj := range mike {
-												relationshipTypesList[obj.Name] = struct {
-													id               string
-													typepair         string
-													leadobjecttypeid string
-												}{id, obj.RelationshipTypePairs[0].RelationshipTypePairId, obj.RelationshipTypePairs[0].LeadObjectTypeId}
+											for _, obj := range mike {
+												relationshipTypesList[obj.Name] = obj
 												selects = append(selects, obj.Name)
 											}
 											relationshipSelect.SetOptions(selects)
@@ -308,6 +375,12 @@ func createRelationshipWindow(
 					addRelWindow.Show()
 				},
 			),
+			widget.NewToolbarAction(
+				theme.ContentPasteIcon(),
+				func() {
+					showBulkRelationshipWindow(basics)
+				},
+			),
 			widget.NewToolbarAction(
 				theme.ContentRemoveIcon(),
 				func() {
@@ -339,86 +412,7 @@ func createRelationshipWindow(
 			),
 			widget.NewToolbarAction(
 				theme.ColorPaletteIcon(),
-				func() {
-					filename := widget.NewEntry()
-					dialog.ShowForm(
-						"Save diagram",
-						"Save",
-						"Don't",
-						[]*widget.FormItem{widget.NewFormItem("Filename", filename)},
-						func(save bool) {
-							if !save {
-								return
-							}
-							fileName := filepath.Join(getSavePath(), filepath.Base(filename.Text))
-							if len(fileName) < 5 || fileName[len(fileName)-5:] != "puml" {
-								fileName = fileName + ".puml"
-							}
-							fo, err := os.Create(fileName)
-							if err != nil {
-								panic(err)
-							}
-							// close fo on exit and check for its returned error
-							defer func() {
-								if err := fo.Close(); err != nil {
-									panic(err)
-								}
-							}()
-							fo.WriteString(PlantUMLStart)
-							alreadyDrawn := map[string]string{}
-							alreadyDrawn = map[string]string{
-								basics.ObjectId: nameToToken(&alreadyDrawn, basics.Name),
-							}
-							relationships := map[string]relationshipStruct{}
-							objects := map[string]objectStruct{}
-							addToObjectStruct(&objects, alreadyDrawn[basics.ObjectId], basics.Name, "physical application component")
-							for _, x := range selectedRelations {
-								leftAlias := ""
-								rightAlias := ""
-								var y bool
-								if leftAlias, y = alreadyDrawn[x.LeadObjectId]; !y {
-									leftAlias = nameToToken(&alreadyDrawn, x.LeadObject.Name)
-									alreadyDrawn[x.LeadObjectId] = leftAlias
-									addToObjectStruct(&objects, alreadyDrawn[x.LeadObjectId], x.LeadObject.Name, x.LeadObject.Type.Name)
-								}
-								if rightAlias, y = alreadyDrawn[x.MemberObjectId]; !y {
-									rightAlias = nameToToken(&alreadyDrawn, x.MemberObject.Name)
-									alreadyDrawn[x.MemberObjectId] = rightAlias
-									addToObjectStruct(&objects, alreadyDrawn[x.MemberObjectId], x.MemberObject.Name, x.MemberObject.Type.Name)
-								}
-								addRelationship(
-									&relationships,
-									&objects,
-									leftAlias,
-									x.LeadObject,
-									rightAlias,
-									x.MemberObject,
-									x.RelationshipId,
-									x.RelationshipType.LeadToMemberDirection,
-								)
-							}
-							for _, x := range objects {
-								fo.WriteString(drawObject(x))
-							}
-							for _, x := range relationships {
-								fo.WriteString(
-									fmt.Sprintf(
-										"Rel(%s,%s,\"%s\")\n",
-										x.leftAlias,
-										x.rightAlias,
-										x.relationshipName,
-									))
-							}
-							fo.WriteString(PlantUMLEnd)
-							dialog.ShowInformation(
-								"Saved",
-								fmt.Sprintf("Saved the diagram to %s", fileName),
-								*thenWindow,
-							)
-						},
-						*thenWindow,
-					)
-				},
+				askToSave,
 			),
 			widget.NewToolbarAction(
 				theme.ViewRefreshIcon(),
@@ -430,16 +424,49 @@ func createRelationshipWindow(
 						knownKids[""] = append(knownKids[""], x.RelationshipId)
 						knownBits[x.RelationshipId] = x
 					}
-					returningContainer.Objects[0] = createRelationshipList(
+					expandedObjects = map[string]bool{basics.ObjectId: true}
+					relationshipSplit.Leading = createRelationshipList(
 						selectedRelations,
 						knownKids,
-						knownBits)
+						knownBits,
+						expandedObjects,
+						definition.Directions,
+						onToggle)
+					relationshipSplit.Refresh()
+				},
+			),
+			widget.NewToolbarAction(
+				theme.SearchIcon(),
+				func() {
+					showExplorerWindow(basics, thenWindow, showExplored)
+				},
+			),
+			widget.NewToolbarAction(
+				theme.ListIcon(),
+				func() {
+					showViewpointWindow(basics, definition, func(result explorerResult) {
+						for i := range selectedRelations {
+							delete(selectedRelations, i)
+						}
+						for i, x := range result.relations {
+							selectedRelations[i] = x
+						}
+						showExplored(result)
+						onToggle()
+						askToSave()
+					})
+				},
+			),
+			widget.NewToolbarAction(
+				theme.GridIcon(),
+				func() {
+					showLayoutWindow(basics, selectedRelations, definition, onToggle)
 				},
 			)),
 		nil,
 		nil,
 		container.NewGridWithColumns(1, widget.NewLabel(""), widget.NewLabel(""), widget.NewLabel(""), widget.NewLabel(""), widget.NewLabel(""), widget.NewLabel(""), widget.NewLabel("")),
-		relationshipList,
+		relationshipSplit,
 	)
 	return returningContainer
 }
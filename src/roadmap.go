@@ -0,0 +1,538 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	azure "vonexplaino.com/m/v2/vondiagram/azure"
+)
+
+/**
+** Roadmap - a lifecycle timeline of applications and technology, from the
+** Internal and Vendor dates on each object. Written as a PlantUML gantt, a
+** Mermaid gantt and an HTML page of swimlanes by product manager.
+**/
+
+//go:embed roadmap.html
+var roadmapTemplate string
+
+var (
+	roadmapInDevelopment = "Internal: In Development From"
+	roadmapLive          = "Internal: Live date"
+	roadmapPhaseOut      = "Internal: Phase Out From"
+	roadmapRetirement    = "Internal: Retirement date"
+	roadmapContained     = "Vendor: Contained From"
+	roadmapOutOfSupport  = "Vendor: Out of Support"
+	roadmapLastReview    = "Last Standard Review Date"
+	roadmapNextReview    = "Next Standard Review Date"
+)
+
+// iServer isn't consistent with the case of some date names, so ask for both
+var roadmapAttributes = []string{
+	"Owner",
+	"Lifecycle Status",
+	roadmapInDevelopment,
+	roadmapLive,
+	"Internal: Live Date",
+	roadmapPhaseOut,
+	roadmapRetirement,
+	"Internal: Retirement Date",
+	roadmapContained,
+	roadmapOutOfSupport,
+	roadmapLastReview,
+	roadmapNextReview,
+}
+
+var roadmapObjectTypes = []string{"Physical Application Component", "Physical Technology Component"}
+
+type roadmapPhase struct {
+	Name       string
+	Start, End time.Time
+	// No later date ends the phase, so it runs to the end of the roadmap
+	Open bool
+}
+
+type roadmapMilestone struct {
+	Name string
+	Date time.Time
+	Flag bool
+}
+
+type roadmapItem struct {
+	ObjectId   string
+	Name       string
+	ObjectType string
+	Owner      string
+	Lifecycle  string
+	Phases     []roadmapPhase
+	Milestones []roadmapMilestone
+	// Why the dates need looking at
+	Warning string
+}
+
+type roadmap struct {
+	Title      string
+	Start, End time.Time
+	Items      []roadmapItem
+}
+
+// Dates come back from iServer as 2024-01-31T00:00:00Z, but may be typed as 2024/01/31
+func parseIServerDate(value string) (time.Time, bool) {
+	value = strings.ReplaceAll(strings.TrimSpace(value), "/", "-")
+	if i := strings.Index(value, "T"); i > -1 {
+		value = value[:i]
+	}
+	when, err := time.Parse("2006-01-02", value)
+	return when, err == nil
+}
+
+func roadmapItemFor(x azure.IServerObjectStruct) roadmapItem {
+	item := roadmapItem{ObjectId: x.ObjectId, Name: x.Name, ObjectType: x.ObjectType.Name}
+	dates := map[string]time.Time{}
+	for _, y := range x.AttributeValues {
+		switch y.AttributeName {
+		case "Owner":
+			item.Owner = y.StringValue
+		case "Lifecycle Status":
+			item.Lifecycle = y.StringValue
+		default:
+			if when, ok := parseIServerDate(y.StringValue); ok {
+				dates[strings.ToLower(y.AttributeName)] = when
+			}
+		}
+	}
+	date := func(name string) (time.Time, bool) {
+		when, ok := dates[strings.ToLower(name)]
+		return when, ok
+	}
+	// Each phase runs until the next one that has a date
+	steps := []string{roadmapInDevelopment, roadmapLive, roadmapPhaseOut, roadmapRetirement}
+	names := []string{"In Development", "Live", "Phasing Out"}
+	for i, name := range names {
+		start, ok := date(steps[i])
+		if !ok {
+			continue
+		}
+		phase := roadmapPhase{Name: name, Start: start, Open: true}
+		for _, next := range steps[i+1:] {
+			if end, ok := date(next); ok {
+				phase.End, phase.Open = end, false
+				break
+			}
+		}
+		item.Phases = append(item.Phases, phase)
+	}
+	retirement, retires := date(roadmapRetirement)
+	if retires {
+		item.Milestones = append(item.Milestones, roadmapMilestone{Name: "Retired", Date: retirement})
+	}
+	if when, ok := date(roadmapContained); ok {
+		item.Milestones = append(item.Milestones, roadmapMilestone{Name: "Vendor contained", Date: when})
+	}
+	if when, ok := date(roadmapOutOfSupport); ok {
+		milestone := roadmapMilestone{Name: "Out of support", Date: when}
+		switch {
+		case !retires:
+			milestone.Flag = true
+			item.Warning = fmt.Sprintf("Vendor support ends %s and there is no retirement date", when.Format("2006-01-02"))
+		case when.Before(retirement):
+			milestone.Flag = true
+			item.Warning = fmt.Sprintf("Vendor support ends %s, before retirement on %s", when.Format("2006-01-02"), retirement.Format("2006-01-02"))
+		}
+		item.Milestones = append(item.Milestones, milestone)
+	}
+	if when, ok := date(roadmapLastReview); ok {
+		item.Milestones = append(item.Milestones, roadmapMilestone{Name: "Standard reviewed", Date: when})
+	}
+	if when, ok := date(roadmapNextReview); ok {
+		item.Milestones = append(item.Milestones, roadmapMilestone{Name: "Standard review", Date: when})
+	}
+	return item
+}
+
+// Build the roadmap, closing open phases at the end of the roadmap: the latest date, or a year from today
+func newRoadmap(title string, objects []azure.IServerObjectStruct, today time.Time) roadmap {
+	toReturn := roadmap{Title: title, Start: today, End: today.AddDate(1, 0, 0)}
+	for _, x := range objects {
+		item := roadmapItemFor(x)
+		for _, y := range item.Phases {
+			if y.Start.Before(toReturn.Start) {
+				toReturn.Start = y.Start
+			}
+			if y.End.After(toReturn.End) {
+				toReturn.End = y.End
+			}
+		}
+		for _, y := range item.Milestones {
+			if y.Date.Before(toReturn.Start) {
+				toReturn.Start = y.Date
+			}
+			if y.Date.After(toReturn.End) {
+				toReturn.End = y.Date
+			}
+		}
+		toReturn.Items = append(toReturn.Items, item)
+	}
+	toReturn.Start = time.Date(toReturn.Start.Year(), toReturn.Start.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for i := range toReturn.Items {
+		for j, y := range toReturn.Items[i].Phases {
+			if y.Open {
+				toReturn.Items[i].Phases[j].End = toReturn.End
+			}
+		}
+	}
+	sort.SliceStable(toReturn.Items, func(i, j int) bool {
+		if toReturn.Items[i].ownerLane() != toReturn.Items[j].ownerLane() {
+			return toReturn.Items[i].ownerLane() < toReturn.Items[j].ownerLane()
+		}
+		return toReturn.Items[i].Name < toReturn.Items[j].Name
+	})
+	return toReturn
+}
+
+func (x roadmapItem) ownerLane() string {
+	if strings.TrimSpace(x.Owner) == "" {
+		return "No Product Manager"
+	}
+	return x.Owner
+}
+
+// Items grouped by Owner, in order
+func (r roadmap) lanes() [][]roadmapItem {
+	toReturn := [][]roadmapItem{}
+	for _, x := range r.Items {
+		if len(toReturn) == 0 || toReturn[len(toReturn)-1][0].ownerLane() != x.ownerLane() {
+			toReturn = append(toReturn, []roadmapItem{})
+		}
+		toReturn[len(toReturn)-1] = append(toReturn[len(toReturn)-1], x)
+	}
+	return toReturn
+}
+
+func (r roadmap) warnings() []roadmapItem {
+	toReturn := []roadmapItem{}
+	for _, x := range r.Items {
+		if x.Warning != "" {
+			toReturn = append(toReturn, x)
+		}
+	}
+	return toReturn
+}
+
+// PlantUML gantt task names are in square brackets
+func ganttName(name string) string {
+	return strings.NewReplacer("[", "(", "]", ")").Replace(name)
+}
+
+func roadmapPlantUML(r roadmap) string {
+	toReturn := new(strings.Builder)
+	toReturn.WriteString("@startgantt\n")
+	fmt.Fprintf(toReturn, "title %s\n", r.Title)
+	toReturn.WriteString("printscale monthly\n")
+	fmt.Fprintf(toReturn, "Project starts %s\n", r.Start.Format("2006-01-02"))
+	for _, lane := range r.lanes() {
+		fmt.Fprintf(toReturn, "-- %s --\n", lane[0].ownerLane())
+		for _, x := range lane {
+			for _, y := range x.Phases {
+				task := ganttName(fmt.Sprintf("%s (%s)", x.Name, y.Name))
+				fmt.Fprintf(toReturn, "[%s] starts %s and ends %s\n", task, y.Start.Format("2006-01-02"), y.End.Format("2006-01-02"))
+				fmt.Fprintf(toReturn, "[%s] is colored in #%s\n", task, lifecycleFills[y.Name])
+			}
+			for _, y := range x.Milestones {
+				task := ganttName(fmt.Sprintf("%s %s", x.Name, strings.ToLower(y.Name)))
+				fmt.Fprintf(toReturn, "[%s] happens %s\n", task, y.Date.Format("2006-01-02"))
+				if y.Flag {
+					fmt.Fprintf(toReturn, "[%s] is colored in #%s\n", task, lifecycleFills["Retired"])
+				}
+			}
+		}
+	}
+	toReturn.WriteString("@endgantt\n")
+	return toReturn.String()
+}
+
+// Mermaid splits tasks on colons and treats # and ; as comments and line ends
+var mermaidNoise = strings.NewReplacer(":", " ", "#", "", ";", ",")
+
+func roadmapMermaid(r roadmap) string {
+	tags := map[string]string{"In Development": "", "Live": "active, ", "Phasing Out": "done, "}
+	toReturn := new(strings.Builder)
+	toReturn.WriteString("gantt\n")
+	fmt.Fprintf(toReturn, "    title %s\n", mermaidNoise.Replace(r.Title))
+	toReturn.WriteString("    dateFormat YYYY-MM-DD\n")
+	toReturn.WriteString("    axisFormat %Y-%m\n")
+	for _, lane := range r.lanes() {
+		fmt.Fprintf(toReturn, "    section %s\n", mermaidNoise.Replace(lane[0].ownerLane()))
+		for _, x := range lane {
+			name := mermaidNoise.Replace(x.Name)
+			for _, y := range x.Phases {
+				fmt.Fprintf(toReturn, "    %s %s :%s%s, %s\n", name, y.Name, tags[y.Name], y.Start.Format("2006-01-02"), y.End.Format("2006-01-02"))
+			}
+			for _, y := range x.Milestones {
+				crit := ""
+				if y.Flag {
+					crit = "crit, "
+				}
+				fmt.Fprintf(toReturn, "    %s %s :%smilestone, %s, 0d\n", name, strings.ToLower(y.Name), crit, y.Date.Format("2006-01-02"))
+			}
+		}
+	}
+	return toReturn.String()
+}
+
+var (
+	roadmapLabelWidth = 280
+	roadmapChartWidth = 900
+	roadmapRowHeight  = 24
+	roadmapLaneHeader = 28
+)
+
+func roadmapHTML(r roadmap) string {
+	type bar struct {
+		X, Y, Width int
+		Colour      string
+		Label       string
+	}
+	type mark struct {
+		// A diamond on the row
+		Points string
+		Label  string
+		Flag   bool
+	}
+	type row struct {
+		Y         int
+		Name      string
+		URL       string
+		Warning   string
+		Lifecycle string
+		Bars      []bar
+		Marks     []mark
+	}
+	type lane struct {
+		Y, Height int
+		Owner     string
+		Rows      []row
+	}
+	type tick struct {
+		X     int
+		Label string
+	}
+	span := r.End.Sub(r.Start).Hours()
+	if span <= 0 {
+		span = 1
+	}
+	x := func(when time.Time) int {
+		return roadmapLabelWidth + int(when.Sub(r.Start).Hours()/span*float64(roadmapChartWidth))
+	}
+	lanes := []lane{}
+	y := roadmapLaneHeader
+	for _, items := range r.lanes() {
+		l := lane{Y: y, Owner: items[0].ownerLane()}
+		y += roadmapLaneHeader
+		for _, item := range items {
+			ro := row{Y: y, Name: item.Name, URL: fmt.Sprintf(iServerObjectURL, item.ObjectId), Warning: item.Warning, Lifecycle: item.Lifecycle}
+			for _, p := range item.Phases {
+				ro.Bars = append(ro.Bars, bar{
+					X:      x(p.Start),
+					Y:      y + 2,
+					Width:  max(x(p.End)-x(p.Start), 1),
+					Colour: lifecycleFills[p.Name],
+					Label:  fmt.Sprintf("%s %s to %s", p.Name, p.Start.Format("2006-01-02"), p.End.Format("2006-01-02")),
+				})
+			}
+			for _, m := range item.Milestones {
+				mx, my, size := x(m.Date), y+roadmapRowHeight/2, roadmapRowHeight/2-2
+				ro.Marks = append(ro.Marks, mark{
+					Points: fmt.Sprintf("%d,%d %d,%d %d,%d %d,%d", mx, my-size, mx+size, my, mx, my+size, mx-size, my),
+					Label:  fmt.Sprintf("%s %s", m.Name, m.Date.Format("2006-01-02")),
+					Flag:   m.Flag,
+				})
+			}
+			l.Rows = append(l.Rows, ro)
+			y += roadmapRowHeight
+		}
+		l.Height = y - l.Y
+		lanes = append(lanes, l)
+	}
+	ticks := []tick{}
+	for year := r.Start.Year() + 1; year <= r.End.Year(); year++ {
+		when := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+		ticks = append(ticks, tick{X: x(when), Label: fmt.Sprint(year)})
+	}
+	tmpl, err := template.New("roadmap").Parse(roadmapTemplate)
+	if err != nil {
+		panic(err)
+	}
+	buf := bytes.NewBufferString("")
+	err = tmpl.Execute(buf, struct {
+		Title      string
+		Width      int
+		Height     int
+		BarHeight  int
+		Lanes      []lane
+		Ticks      []tick
+		Today      int
+		Fills      map[string]string
+		Flagged    []roadmapItem
+		FlagColour string
+	}{
+		Title:      r.Title,
+		Width:      roadmapLabelWidth + roadmapChartWidth + 20,
+		Height:     y + 10,
+		BarHeight:  roadmapRowHeight - 4,
+		Lanes:      lanes,
+		Ticks:      ticks,
+		Today:      x(time.Now()),
+		Fills:      lifecycleFills,
+		Flagged:    r.warnings(),
+		FlagColour: lifecycleFills["Retired"],
+	})
+	if err != nil {
+		panic(err)
+	}
+	return buf.String()
+}
+
+// Write the roadmap in each format, returning the files written
+func writeRoadmap(r roadmap, savePath string, when time.Time) ([]string, error) {
+	base := filepath.Join(savePath, fmt.Sprintf("%s %s", strings.TrimSpace(fileNameNoise.ReplaceAllString(r.Title, "")), when.Format("2006-01-02")))
+	written := []string{}
+	for _, x := range []struct {
+		suffix   string
+		contents string
+	}{
+		{".puml", roadmapPlantUML(r)},
+		{".mmd", roadmapMermaid(r)},
+		{".html", roadmapHTML(r)},
+	} {
+		if err := os.WriteFile(base+x.suffix, []byte(x.contents), 0644); err != nil {
+			return written, err
+		}
+		written = append(written, base+x.suffix)
+	}
+	return written, nil
+}
+
+// Roadmappable objects by name, with their roadmap dates. Names that match none, or more than one,
+// are reported rather than guessed at.
+func roadmapObjectsNamed(
+	names []string,
+	find func(string) ([]azure.FindStruct, error),
+	get func(string, []string) (azure.IServerObjectStruct, error)) ([]azure.IServerObjectStruct, error) {
+	toReturn := []azure.IServerObjectStruct{}
+	ids := []string{}
+	missing, ambiguous := []string{}, []string{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		found, err := find(name)
+		if err != nil {
+			return toReturn, fmt.Errorf("could not look up %s: %w", name, err)
+		}
+		matched := []azure.FindStruct{}
+		for _, x := range found {
+			if slices.Contains(roadmapObjectTypes, x.Type.Name) {
+				matched = append(matched, x)
+			}
+		}
+		switch len(matched) {
+		case 0:
+			missing = append(missing, name)
+		case 1:
+			ids = append(ids, matched[0].ObjectId)
+		default:
+			types := []string{}
+			for _, x := range matched {
+				types = append(types, x.Type.Name)
+			}
+			ambiguous = append(ambiguous, fmt.Sprintf("%s (%d: %s)", name, len(matched), strings.Join(types, ", ")))
+		}
+	}
+	problems := []string{}
+	if len(missing) > 0 {
+		problems = append(problems, fmt.Sprintf("no %s called %s", strings.Join(roadmapObjectTypes, " or "), strings.Join(missing, ", ")))
+	}
+	if len(ambiguous) > 0 {
+		problems = append(problems, "more than one object called "+strings.Join(ambiguous, "; "))
+	}
+	if len(problems) > 0 {
+		return toReturn, errors.New(strings.Join(problems, "\n"))
+	}
+	for _, id := range ids {
+		x, err := get(id, roadmapAttributes)
+		if err != nil {
+			return toReturn, err
+		}
+		toReturn = append(toReturn, x)
+	}
+	return toReturn, nil
+}
+
+// Roadmap the domain from Settings, or a list of objects by name
+func showRoadmapDialog(thenWindow fyne.Window) {
+	domain := myApp.Preferences().StringWithFallback("Department", "")
+	source := widget.NewRadioGroup([]string{"Domain " + domain, "These objects"}, func(s string) {})
+	source.SetSelected(source.Options[0])
+	names := widget.NewMultiLineEntry()
+	names.SetPlaceHolder("One object name per line")
+	names.SetMinRowsVisible(5)
+	dialog.ShowForm(
+		"Lifecycle roadmap",
+		"Create",
+		"Cancel",
+		[]*widget.FormItem{
+			widget.NewFormItem("For", source),
+			widget.NewFormItem("Objects", names),
+		},
+		func(ok bool) {
+			if !ok {
+				return
+			}
+			UpdateMessage("Running")
+			go func() {
+				var objects []azure.IServerObjectStruct
+				var err error
+				title := "Roadmap " + domain
+				if source.Selected == source.Options[0] {
+					objects, err = az.GetDomainObjects(domain, roadmapObjectTypes, roadmapAttributes, false)
+				} else {
+					title = "Roadmap"
+					objects, err = roadmapObjectsNamed(strings.Split(names.Text, "\n"), az.FindObjectsByName, az.GetObjectAttributes)
+				}
+				if err == nil {
+					var written []string
+					written, err = writeRoadmap(newRoadmap(title, objects, time.Now()), getSavePath(), time.Now())
+					for _, x := range written {
+						if filepath.Ext(x) != ".html" {
+							continue
+						}
+						location := filepath.ToSlash(x)
+						if !strings.HasPrefix(location, "/") {
+							location = "/" + location
+						}
+						openbrowser("file://" + location)
+					}
+				}
+				UpdateMessage("Ready")
+				if err != nil {
+					dialog.ShowError(err, thenWindow)
+				}
+			}()
+		},
+		thenWindow,
+	)
+}
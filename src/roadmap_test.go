@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	azure "vonexplaino.com/m/v2/vondiagram/azure"
+)
+
+func TestRoadmap(t *testing.T) {
+	testObject := func(id, owner string, attributes ...string) azure.IServerObjectStruct {
+		x := azure.IServerObjectStruct{Name: id, ObjectId: id}
+		x.ObjectType.Name = "Physical Application Component"
+		x.AttributeValues = []azure.AttributeValue{{AttributeName: "Owner", StringValue: owner}}
+		for i := 0; i+1 < len(attributes); i += 2 {
+			x.AttributeValues = append(x.AttributeValues, azure.AttributeValue{AttributeName: attributes[i], StringValue: attributes[i+1]})
+		}
+		return x
+	}
+	today := time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC)
+	r := newRoadmap("Roadmap ITS", []azure.IServerObjectStruct{
+		testObject("Old: App", "Sam",
+			"Internal: Live Date", "2020-02-01T00:00:00Z",
+			"Internal: Phase Out From", "2024-01-01T00:00:00Z",
+			"Internal: Retirement date", "2026-12-31T00:00:00Z",
+			"Vendor: Out of Support", "2026-06-30T00:00:00Z"),
+		testObject("New App", "Pat",
+			"Internal: In Development From", "2025/01/01",
+			"Internal: Live date", "2025-07-01T00:00:00Z"),
+		testObject("Kept App", "Pat",
+			"Internal: Live date", "2021-01-01T00:00:00Z",
+			"Vendor: Out of Support", "2027-01-01T00:00:00Z"),
+	}, today)
+
+	assert.Equal(t, time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC), r.Start)
+	assert.Equal(t, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), r.End)
+	assert.Equal(t, []string{"Kept App", "New App", "Old: App"}, []string{r.Items[0].Name, r.Items[1].Name, r.Items[2].Name})
+	assert.Equal(t, []roadmapPhase{
+		{Name: "In Development", Start: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{Name: "Live", Start: time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC), End: r.End, Open: true},
+	}, r.Items[1].Phases)
+	assert.Equal(t, "Vendor support ends 2027-01-01 and there is no retirement date", r.Items[0].Warning)
+	assert.Equal(t, "Vendor support ends 2026-06-30, before retirement on 2026-12-31", r.Items[2].Warning)
+	assert.Len(t, r.warnings(), 2)
+
+	puml := roadmapPlantUML(r)
+	assert.Contains(t, puml, "@startgantt\ntitle Roadmap ITS\nprintscale monthly\nProject starts 2020-02-01\n-- Pat --\n")
+	assert.Contains(t, puml, "[New App (Live)] starts 2025-07-01 and ends 2027-01-01\n[New App (Live)] is colored in #63BE7B\n")
+	assert.Contains(t, puml, "[Old: App out of support] happens 2026-06-30\n[Old: App out of support] is colored in #F8696B\n")
+	assert.True(t, strings.HasSuffix(puml, "@endgantt\n"))
+
+	mermaid := roadmapMermaid(r)
+	assert.Contains(t, mermaid, "    section Sam\n    Old  App Live :active, 2020-02-01, 2024-01-01\n")
+	assert.Contains(t, mermaid, "    Old  App out of support :crit, milestone, 2026-06-30, 0d\n")
+
+	html := roadmapHTML(r)
+	assert.Contains(t, html, `<text class="owner" x="4" y="28" dy="18">Pat</text>`)
+	assert.Contains(t, html, `href="https://griffith.iserver365.com/object/New%20App/details"`)
+	assert.Contains(t, html, "<li>Old: App (Sam): Vendor support ends 2026-06-30, before retirement on 2026-12-31</li>")
+}
+
+func TestRoadmapObjectsNamed(t *testing.T) {
+	found := func(id, name, objectType string) azure.FindStruct {
+		x := azure.FindStruct{ObjectId: id, Name: name}
+		x.Type.Name = objectType
+		return x
+	}
+	iserver := map[string][]azure.FindStruct{
+		"Payroll": {found("p1", "Payroll", "Physical Application Component"), found("c1", "Payroll", "Capability")},
+		"Twice":   {found("t1", "Twice", "Physical Application Component"), found("t2", "Twice", "Physical Technology Component")},
+		"Idea":    {found("i1", "Idea", "Capability")},
+	}
+	find := func(name string) ([]azure.FindStruct, error) { return iserver[name], nil }
+	fetched := []string{}
+	get := func(id string, attributes []string) (azure.IServerObjectStruct, error) {
+		fetched = append(fetched, id)
+		return azure.IServerObjectStruct{ObjectId: id}, nil
+	}
+
+	objects, err := roadmapObjectsNamed([]string{"Payroll", " ", ""}, find, get)
+	assert.NoError(t, err)
+	assert.Equal(t, []azure.IServerObjectStruct{{ObjectId: "p1"}}, objects, "only the roadmappable Payroll")
+
+	fetched = []string{}
+	_, err = roadmapObjectsNamed([]string{"Payroll", "Twice", "Idea"}, find, get)
+	assert.EqualError(t, err, "no Physical Application Component or Physical Technology Component called Idea\n"+
+		"more than one object called Twice (2: Physical Application Component, Physical Technology Component)")
+	assert.Empty(t, fetched, "nothing fetched until every name is resolved")
+
+	_, err = roadmapObjectsNamed([]string{"Payroll"}, func(string) ([]azure.FindStruct, error) {
+		return nil, errors.New("iserver query failure, received 401")
+	}, get)
+	assert.EqualError(t, err, "could not look up Payroll: iserver query failure, received 401")
+}
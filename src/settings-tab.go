@@ -13,15 +13,29 @@ func makeSettingsTab(dept *widget.Select) *fyne.Container {
 	pms.SetMinRowsVisible(7)
 	savepath := widget.NewEntry()
 	savepath.SetText(myApp.Preferences().StringWithFallback("SavePath", ""))
+	plantumljar := widget.NewEntry()
+	plantumljar.SetPlaceHolder("Path to plantuml.jar, blank for the built-in preview")
+	plantumljar.SetText(myApp.Preferences().StringWithFallback("PlantUMLJar", ""))
+	hermmodel := widget.NewEntry()
+	hermmodel.SetPlaceHolder("CAUDIT HERM capabilities CSV or JSON, needed for capability coverage")
+	hermmodel.SetText(myApp.Preferences().StringWithFallback("HERMModel", ""))
+	selfcontained := widget.NewCheck("Inline TOGAF definitions (renders offline)", func(b bool) {})
+	selfcontained.SetChecked(myApp.Preferences().BoolWithFallback("SelfContained", false))
 	return container.NewVBox(
 		widget.NewForm(
 			widget.NewFormItem("Domains", dept),
 			widget.NewFormItem("Product Managers", pms),
 			widget.NewFormItem("Save path", savepath),
+			widget.NewFormItem("Diagrams", selfcontained),
+			widget.NewFormItem("PlantUML jar", plantumljar),
+			widget.NewFormItem("HERM model", hermmodel),
 		),
 		widget.NewButton("Save", func() {
 			myApp.Preferences().SetString("Department", dept.Selected)
 			myApp.Preferences().SetString("ProductManagers", PrettyJSONString(pms.Text))
 			myApp.Preferences().SetString("SavePath", savepath.Text)
+			myApp.Preferences().SetBool("SelfContained", selfcontained.Checked)
+			myApp.Preferences().SetString("PlantUMLJar", plantumljar.Text)
+			myApp.Preferences().SetString("HERMModel", hermmodel.Text)
 		}))
 }
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	azure "vonexplaino.com/m/v2/vondiagram/azure"
+)
+
+/**
+** Viewpoints - named TOGAF diagram templates. Each picks the object and
+** relationship types to walk out to from the root, and which types group
+** the others, so a diagram can be produced without ticking relationships.
+**/
+
+type viewpoint struct {
+	Name        string
+	Description string
+	Depth       int
+	ObjectTypes []string
+	// Relationship names or directions to follow; empty follows them all
+	Follow []string
+	Skip   []string
+	// Object types drawn as boundaries, outermost first
+	GroupTypes []string
+	LayoutMode string
+}
+
+var ownershipRelations = []string{"is owned by", "owns", "is responsible for", "is accountable for"}
+
+var viewpoints = []viewpoint{
+	{
+		Name:        "Solution Concept",
+		Description: "High level sketch of the solution: the components, who uses them and where they live",
+		Depth:       1,
+		ObjectTypes: []string{
+			"Actor",
+			"Capability",
+			"Location",
+			"Logical Application Component",
+			"Organization Unit",
+			"Physical Application Component",
+			"Physical Technology Component",
+		},
+		Follow:     []string{"uses", "is used by", "realises", "is realised by", "is hosted on", "hosts", "is located at"},
+		Skip:       ownershipRelations,
+		GroupTypes: []string{"Location"},
+		LayoutMode: "TOP_DOWN",
+	},
+	{
+		Name:        "Application Communication",
+		Description: "Applications the root talks to, and the interfaces they talk through",
+		Depth:       2,
+		ObjectTypes: []string{
+			"Interface",
+			"Physical Application Component",
+		},
+		Follow:     []string{"uses", "is used by", "serves", "is served by", "communicates with", "flows to"},
+		Skip:       ownershipRelations,
+		LayoutMode: "LEFT_RIGHT",
+	},
+	{
+		Name:        "Application/Data",
+		Description: "Data components and entities the applications create, read and hold",
+		Depth:       2,
+		ObjectTypes: []string{
+			"Data Entity",
+			"Logical Data Component",
+			"Physical Application Component",
+			"Physical Data Component",
+		},
+		Follow:     []string{"uses", "is used by", "accesses", "is accessed by", "creates", "reads", "holds", "contains", "realises", "is realised by"},
+		Skip:       ownershipRelations,
+		GroupTypes: []string{"Logical Data Component"},
+		LayoutMode: "LEFT_RIGHT",
+	},
+	{
+		Name:        "Application/Technology",
+		Description: "Deployment view: the technology each application runs on, and where that technology is",
+		Depth:       2,
+		ObjectTypes: []string{
+			"Location",
+			"Physical Application Component",
+			"Physical Technology Component",
+			"Physical Technology Group",
+		},
+		Follow:     []string{"is hosted on", "hosts", "is located at", "contains", "is contained in", "uses"},
+		Skip:       ownershipRelations,
+		GroupTypes: []string{"Location", "Physical Technology Group", "Physical Technology Component"},
+		LayoutMode: "TOP_DOWN",
+	},
+	{
+		Name:        "Capability Realisation",
+		Description: "Capabilities and the services and components that realise them",
+		Depth:       2,
+		ObjectTypes: []string{
+			"Application Service",
+			"Business Service",
+			"Capability",
+			"Logical Application Component",
+			"Physical Application Component",
+		},
+		Follow:     []string{"realises", "is realised by", "supports", "is supported by", "serves", "uses"},
+		Skip:       ownershipRelations,
+		GroupTypes: []string{"Capability"},
+		LayoutMode: "TOP_DOWN",
+	},
+}
+
+func viewpointNamed(name string) (viewpoint, bool) {
+	for _, x := range viewpoints {
+		if x.Name == name {
+			return x, true
+		}
+	}
+	return viewpoint{}, false
+}
+
+func (v viewpoint) explorerOptions() explorerOptions {
+	options := explorerOptions{
+		depth:         v.Depth,
+		includeTypes:  map[string]bool{},
+		skipRelations: map[string]bool{},
+		onlyRelations: map[string]bool{},
+	}
+	for _, x := range v.ObjectTypes {
+		options.includeTypes[x] = true
+	}
+	for _, x := range v.Skip {
+		options.skipRelations[strings.ToLower(x)] = true
+	}
+	for _, x := range v.Follow {
+		options.onlyRelations[strings.ToLower(x)] = true
+	}
+	return options
+}
+
+// Walk out from the root following the viewpoint, returning everything found as selected
+func (v viewpoint) apply(basics azure.IServerObjectStruct, definition *diagramDefinition, fetch func(string) []azure.RelationStruct) explorerResult {
+	result := exploreFrom(basics.ObjectId, v.explorerOptions(), fetch)
+	definition.GroupTypes = append([]string{}, v.GroupTypes...)
+	definition.LayoutMode = v.LayoutMode
+	return result
+}
+
+// Choose a viewpoint; the explored relationships are handed back already selected
+func showViewpointWindow(basics azure.IServerObjectStruct, definition *diagramDefinition, putInto func(explorerResult)) {
+	viewpointWindow := addWindowFor("Diagram viewpoint", 450, 250)
+	names := []string{}
+	for _, x := range viewpoints {
+		names = append(names, x.Name)
+	}
+	description := widget.NewLabel("")
+	description.Wrapping = fyne.TextWrapWord
+	chosen := widget.NewSelect(names, func(s string) {
+		if x, ok := viewpointNamed(s); ok {
+			description.SetText(x.Description)
+		}
+	})
+	chosen.SetSelected(names[0])
+	viewpointWindow.SetContent(container.NewBorder(
+		widget.NewForm(widget.NewFormItem("Viewpoint", chosen)),
+		widget.NewButton("Generate", func() {
+			x, ok := viewpointNamed(chosen.Selected)
+			if !ok {
+				return
+			}
+			viewpointWindow.Close()
+			UpdateMessage("Generating " + x.Name)
+			go func() {
+				result := x.apply(basics, definition, az.FindRelations)
+				UpdateMessage("Ready")
+				putInto(result)
+			}()
+		}),
+		nil,
+		nil,
+		description,
+	))
+	viewpointWindow.Show()
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	azure "vonexplaino.com/m/v2/vondiagram/azure"
+)
+
+func TestViewpointDeployment(t *testing.T) {
+	deployment, ok := viewpointNamed("Application/Technology")
+	assert.True(t, ok)
+	relations := map[string][]azure.RelationStruct{
+		"pac": {
+			testRelation("r1", "pac", "Physical Application Component", "is hosted on", "ptc", "Physical Technology Component"),
+			testRelation("r2", "pac", "Physical Application Component", "is owned by", "org", "Organization Unit"),
+			testRelation("r3", "pac", "Physical Application Component", "is owned by", "ptc2", "Physical Technology Component"),
+		},
+		"ptc": {
+			testRelation("r1", "pac", "Physical Application Component", "is hosted on", "ptc", "Physical Technology Component"),
+			testRelation("r4", "ptc", "Physical Technology Component", "is located at", "loc", "Location"),
+		},
+	}
+	basics := azure.IServerObjectStruct{ObjectId: "pac", Name: "pac"}
+	definition := diagramDefinition{}
+	result := deployment.apply(basics, &definition, func(id string) []azure.RelationStruct { return relations[id] })
+	assert.ElementsMatch(t, []string{"r1", "r4"}, getMapRelationKeys(result.relations))
+	assert.Equal(t, "TOP_DOWN", definition.LayoutMode)
+
+	puml := diagramPlantUML(basics, result.relations, definition)
+	loc := strings.Index(puml, "System_Boundary(loc,\"loc\",$tags=\"loc\") {")
+	ptc := strings.Index(puml, "System_Boundary(ptc,\"ptc\",$tags=\"ptc\") {")
+	pac := strings.Index(puml, "System_Boundary(pac,\"pac\",$tags=\"pac\")")
+	assert.True(t, loc >= 0 && ptc > loc && pac > ptc, "expected pac inside ptc inside location:\n%s", puml)
+	assert.NotContains(t, puml, "Rel(")
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+	"github.com/xuri/excelize/v2"
+	azure "vonexplaino.com/m/v2/vondiagram/azure"
+)
+
+/**
+** Workbook import - reads a domain workbook back after product managers
+** have corrected it, compares each row with iServer and, once the changes
+** have been reviewed, saves them. The outcome for each row is written into
+** a results column of the workbook.
+**/
+
+var importResultHeader = "Import result"
+
+type importRow struct {
+	sheet    string
+	row      int
+	objectId string
+	name     string
+	// Attribute name to the value in the sheet
+	values map[string]string
+}
+
+type importChange struct {
+	column   auditColumn
+	old, new string
+}
+
+type importPlan struct {
+	importRow
+	current azure.IServerObjectStruct
+	changes []importChange
+	// Why the row can't be applied
+	problem string
+}
+
+// Read the object sheets, keyed on the Object ID in column A
+func readAuditWorkbook(f *excelize.File) ([]importRow, error) {
+	toReturn := []importRow{}
+	found := false
+	for _, sheet := range auditSheets {
+		if idx, err := f.GetSheetIndex(sheet.Sheet); err != nil || idx == -1 {
+			continue
+		}
+		found = true
+		rows, err := f.GetRows(sheet.Sheet)
+		if err != nil {
+			return toReturn, err
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		columns := map[int]auditColumn{}
+		for i, x := range rows[0] {
+			for _, y := range auditColumns {
+				if strings.EqualFold(strings.TrimSpace(x), y.Header) {
+					columns[i] = y
+				}
+			}
+		}
+		for i, x := range rows[1:] {
+			if len(x) == 0 || strings.TrimSpace(x[0]) == "" {
+				continue
+			}
+			row := importRow{sheet: sheet.Sheet, row: i + 2, objectId: strings.TrimSpace(x[0]), values: map[string]string{}}
+			if len(x) > 1 {
+				row.name = x[1]
+			}
+			for j, y := range columns {
+				value := ""
+				if j < len(x) {
+					value = x[j]
+				}
+				row.values[y.Attribute] = normaliseImportValue(value)
+			}
+			toReturn = append(toReturn, row)
+		}
+	}
+	if !found {
+		return toReturn, fmt.Errorf("no Applications, Technology or Logical sheet in the workbook")
+	}
+	return toReturn, nil
+}
+
+func normaliseImportValue(value string) string {
+	return strings.TrimSpace(strings.ReplaceAll(value, "\r\n", "\n"))
+}
+
+// Compare each row with what iServer has now, and check chosen values are valid
+func planImport(rows []importRow, fetch func(string) (azure.IServerObjectStruct, error)) []importPlan {
+	toReturn := []importPlan{}
+	for _, x := range rows {
+		plan := importPlan{importRow: x}
+		current, err := fetch(x.objectId)
+		if err != nil {
+			plan.problem = "Not found in iServer: " + err.Error()
+			toReturn = append(toReturn, plan)
+			continue
+		}
+		plan.current = current
+		currentValues := map[string]string{}
+		for _, y := range current.AttributeValues {
+			currentValues[y.AttributeName] = normaliseImportValue(y.StringValue)
+		}
+		problems := []string{}
+		for _, y := range auditColumns {
+			value, ok := x.values[y.Attribute]
+			if !ok || value == currentValues[y.Attribute] {
+				continue
+			}
+			if y.Category == "Choice" && len(azure.ValidChoices[y.Attribute]) > 0 {
+				if _, valid := azure.ValidChoices[y.Attribute][value]; !valid {
+					problems = append(problems, fmt.Sprintf("%q is not a valid %s", value, y.Header))
+					continue
+				}
+			}
+			plan.changes = append(plan.changes, importChange{column: y, old: currentValues[y.Attribute], new: value})
+		}
+		plan.problem = strings.Join(problems, "; ")
+		toReturn = append(toReturn, plan)
+	}
+	return toReturn
+}
+
+func (x importChange) String() string {
+	return fmt.Sprintf("%s: %q → %q", x.column.Header, x.old, x.new)
+}
+
+// Save the chosen plans, writing the outcome of every row into the results column
+func applyImport(
+	f *excelize.File,
+	plans []importPlan,
+	chosen func(int) bool,
+	patch func(id, name, objectType string, stringValues, selectValues map[string]string) (bool, string),
+	progress func(done, total int)) (int, []string) {
+	applied := 0
+	failures := []string{}
+	for i, x := range plans {
+		progress(i+1, len(plans))
+		result := ""
+		switch {
+		case len(x.changes) == 0 && x.problem != "":
+			result = "Skipped: " + x.problem
+		case len(x.changes) == 0:
+			result = "No changes"
+		case !chosen(i):
+			result = "Not applied"
+		default:
+			stringValues := map[string]string{}
+			selectValues := map[string]string{}
+			headers := []string{}
+			for _, y := range x.changes {
+				if y.column.Category == "Choice" {
+					selectValues[y.column.Attribute] = y.new
+				} else {
+					stringValues[y.column.Attribute] = y.new
+				}
+				headers = append(headers, y.column.Header)
+			}
+			success, message := patch(x.objectId, x.current.Name, x.current.ObjectType.Name, stringValues, selectValues)
+			if success {
+				applied++
+				result = "Updated " + strings.Join(headers, ", ")
+				if x.problem != "" {
+					result += "; skipped " + x.problem
+				}
+			} else {
+				result = "Failed: " + message
+				failures = append(failures, fmt.Sprintf("%s: %s", x.name, message))
+			}
+		}
+		writeImportResult(f, x.sheet, x.row, result)
+	}
+	return applied, failures
+}
+
+func writeImportResult(f *excelize.File, sheet string, row int, result string) {
+	headers, _ := f.GetRows(sheet)
+	column := 0
+	if len(headers) > 0 {
+		column = len(headers[0]) + 1
+		for i, x := range headers[0] {
+			if x == importResultHeader {
+				column = i + 1
+			}
+		}
+	}
+	if column == 0 {
+		return
+	}
+	cell, _ := excelize.CoordinatesToCellName(column, 1)
+	f.SetCellValue(sheet, cell, importResultHeader)
+	cell, _ = excelize.CoordinatesToCellName(column, row)
+	f.SetCellValue(sheet, cell, result)
+}
+
+// Pick an edited workbook, then review what would change before saving to iServer
+func showWorkbookImportDialog(thenWindow fyne.Window) {
+	d := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, thenWindow)
+			return
+		}
+		if reader == nil {
+			return
+		}
+		reader.Close()
+		fileName := reader.URI().Path()
+		f, err := excelize.OpenFile(fileName)
+		if err != nil {
+			dialog.ShowError(err, thenWindow)
+			return
+		}
+		rows, err := readAuditWorkbook(f)
+		if err != nil {
+			f.Close()
+			dialog.ShowError(err, thenWindow)
+			return
+		}
+		go func() {
+			for _, x := range auditColumns {
+				if x.Category == "Choice" && len(azure.ValidChoices[x.Attribute]) == 0 {
+					azure.ValidChoices[x.Attribute] = az.GetChoicesForName(x.Attribute)
+				}
+			}
+			attributes := []string{}
+			for _, x := range auditColumns {
+				attributes = append(attributes, x.Attribute)
+			}
+			fetched := 0
+			plans := planImport(rows, func(id string) (azure.IServerObjectStruct, error) {
+				fetched++
+				UpdateMessage(fmt.Sprintf("Checking %d of %d", fetched, len(rows)))
+				return az.GetObjectAttributes(id, attributes)
+			})
+			UpdateMessage("Ready")
+			showWorkbookImportWindow(f, fileName, plans)
+		}()
+	}, thenWindow)
+	d.SetFilter(storage.NewExtensionFileFilter([]string{".xlsx"}))
+	d.Show()
+}
+
+func showWorkbookImportWindow(f *excelize.File, fileName string, plans []importPlan) {
+	importWindow := addWindowFor("Import "+fileName, 700, 500)
+	importWindow.SetOnClosed(func() { f.Close() })
+
+	ticks := map[int]*widget.Check{}
+	changes := container.NewVBox()
+	problems := container.NewVBox()
+	changed, problemCount := 0, 0
+	for i, x := range plans {
+		if len(x.changes) > 0 {
+			changed++
+			lines := []string{}
+			for _, y := range x.changes {
+				lines = append(lines, "    "+y.String())
+			}
+			ticks[i] = widget.NewCheck(fmt.Sprintf("%s (%s row %d)", x.name, x.sheet, x.row), func(b bool) {})
+			ticks[i].SetChecked(true)
+			changes.Add(ticks[i])
+			changes.Add(widget.NewLabel(strings.Join(lines, "\n")))
+		}
+		if x.problem != "" {
+			problemCount++
+			problems.Add(widget.NewLabel(fmt.Sprintf("%s (%s row %d): %s", x.name, x.sheet, x.row, x.problem)))
+		}
+	}
+	if changed == 0 {
+		changes.Add(widget.NewLabel("Nothing in the workbook differs from iServer"))
+	}
+	if problemCount == 0 {
+		problems.Add(widget.NewLabel("No problems found"))
+	}
+
+	var apply *widget.Button
+	apply = widget.NewButton("Apply ticked changes", func() {
+		apply.Disable()
+		go func() {
+			applied, failures := applyImport(
+				f,
+				plans,
+				func(i int) bool { return ticks[i] != nil && ticks[i].Checked },
+				az.PatchObjectAttributes,
+				func(done, total int) { UpdateMessage(fmt.Sprintf("Importing %d of %d", done, total)) },
+			)
+			UpdateMessage("Ready")
+			if err := f.Save(); err != nil {
+				dialog.ShowError(fmt.Errorf("updated %d objects, but the results could not be written: %w", applied, err), importWindow)
+				return
+			}
+			if len(failures) == 0 {
+				dialog.ShowInformation("Import", fmt.Sprintf("Updated %d objects; results written to the %s column", applied, importResultHeader), importWindow)
+			} else {
+				dialog.ShowError(fmt.Errorf("updated %d objects, but:\n%s", applied, strings.Join(failures, "\n")), importWindow)
+			}
+		}()
+	})
+	if changed == 0 {
+		apply.Disable()
+	}
+
+	importWindow.SetContent(container.NewBorder(
+		widget.NewLabel(fmt.Sprintf("%d rows read, %d with changes, %d with problems", len(plans), changed, problemCount)),
+		apply,
+		nil,
+		nil,
+		container.NewAppTabs(
+			container.NewTabItem("Changes", container.NewVScroll(changes)),
+			container.NewTabItem("Problems", container.NewVScroll(problems)),
+		),
+	))
+	importWindow.Show()
+}
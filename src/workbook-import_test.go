@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+	azure "vonexplaino.com/m/v2/vondiagram/azure"
+)
+
+func TestWorkbookImport(t *testing.T) {
+	testObject := func(id, owner, lifecycle string) azure.IServerObjectStruct {
+		x := azure.IServerObjectStruct{Name: id, ObjectId: id}
+		x.ObjectType.Name = "Physical Application Component"
+		x.AttributeValues = []azure.AttributeValue{
+			{AttributeName: "Owner", StringValue: owner},
+			{AttributeName: "Lifecycle Status", StringValue: lifecycle},
+		}
+		return x
+	}
+	current := map[string]azure.IServerObjectStruct{
+		"A": testObject("A", "Pat", "Live"),
+		"B": testObject("B", "Sam", "Live"),
+		"C": testObject("C", "Lee", "Live"),
+	}
+	audit, relations := buildAuditObjects(
+		[]azure.IServerObjectStruct{current["A"], current["B"], current["C"]},
+		func(string) []azure.RelationStruct { return []azure.RelationStruct{} },
+	)
+	fileName := filepath.Join(t.TempDir(), "audit.xlsx")
+	assert.NoError(t, createDomainWorkbook(audit, relations, nil, fileName))
+
+	f, err := excelize.OpenFile(fileName)
+	assert.NoError(t, err)
+	defer f.Close()
+	f.SetCellValue("Applications", "C2", "Alex")
+	f.SetCellValue("Applications", "F2", "Phasing Out")
+	f.SetCellValue("Applications", "F3", "Dead")
+	f.SetCellValue("Applications", "A5", "D")
+
+	rows, err := readAuditWorkbook(f)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 4)
+	assert.Equal(t, "Alex", rows[0].values["Owner"])
+
+	azure.ValidChoices["Lifecycle Status"] = map[string]string{"Live": "1", "Phasing Out": "2"}
+	defer delete(azure.ValidChoices, "Lifecycle Status")
+	plans := planImport(rows, func(id string) (azure.IServerObjectStruct, error) {
+		if x, ok := current[id]; ok {
+			return x, nil
+		}
+		return azure.IServerObjectStruct{}, fmt.Errorf("no object %s", id)
+	})
+	assert.Len(t, plans[0].changes, 2)
+	assert.Equal(t, `Product Manager: "Pat" → "Alex"`, plans[0].changes[0].String())
+	assert.Empty(t, plans[1].changes)
+	assert.Equal(t, `"Dead" is not a valid Lifecycle`, plans[1].problem)
+	assert.Empty(t, plans[2].changes)
+	assert.Contains(t, plans[3].problem, "Not found in iServer")
+
+	patched := map[string][]map[string]string{}
+	applied, failures := applyImport(
+		f,
+		plans,
+		func(int) bool { return true },
+		func(id, name, objectType string, stringValues, selectValues map[string]string) (bool, string) {
+			patched[id] = []map[string]string{stringValues, selectValues}
+			return true, ""
+		},
+		func(int, int) {},
+	)
+	assert.Equal(t, 1, applied)
+	assert.Empty(t, failures)
+	assert.Equal(t, []map[string]string{{"Owner": "Alex"}, {"Lifecycle Status": "Phasing Out"}}, patched["A"])
+	results := []string{}
+	for _, x := range []string{"L2", "L3", "L4", "L5"} {
+		value, _ := f.GetCellValue("Applications", x)
+		results = append(results, value)
+	}
+	header, _ := f.GetCellValue("Applications", "L1")
+	assert.Equal(t, importResultHeader, header)
+	assert.Equal(t, []string{"Updated Product Manager, Lifecycle", `Skipped: "Dead" is not a valid Lifecycle`, "No changes", "Skipped: Not found in iServer: no object D"}, results)
+}